@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"yamb/accounts"
+	"yamb/game"
+	"yamb/views"
+)
+
+var (
+	accountStore *accounts.Store
+	// login codes are printed to the server log, meant for local setups (LOGIN_CODES=true)
+	loginCodes bool
+	// throttles wrong passwords and codes per IP
+	loginThrottle = NewThrottle(10, 10*time.Minute)
+)
+
+// currentAccount returns the logged in account, nil for anonymous players
+func currentAccount(r *http.Request) *accounts.Account {
+	if accountStore == nil {
+		return nil
+	}
+	sessionCookie, err := r.Cookie("session")
+	if err != nil {
+		return nil
+	}
+	acc, err := accountStore.Session(sessionCookie.Value)
+	if err != nil {
+		if !errors.Is(err, accounts.ErrNoSession) {
+			log.Println("error reading session:", err)
+		}
+		return nil
+	}
+	return acc
+}
+
+// rebindAccount moves the seat of the account in the room to the token, the
+// session lets a player come back from another browser. It returns the id of
+// the player, empty when the account has not joined the room.
+func rebindAccount(room *game.Room, acc *accounts.Account, token string) string {
+	playerID := room.AccountPlayer(acc.ID)
+	if playerID == "" {
+		return ""
+	}
+	if err := room.Rebind(playerID, token); err != nil {
+		log.Println("error moving seat of account:", err)
+		return ""
+	}
+	if err := saveRoom(room); err != nil {
+		return ""
+	}
+	return playerID
+}
+
+// startSession logs the account in, rooms are joined with the token of the
+// browser and the account only recorded on the player
+func startSession(w http.ResponseWriter, acc *accounts.Account) error {
+	token, err := accountStore.NewSession(acc.ID)
+	if err != nil {
+		return err
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session",
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int((30 * 24 * time.Hour).Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Header().Set("HX-Redirect", "/")
+	return nil
+}
+
+func LoginPageHandler(w http.ResponseWriter, r *http.Request) {
+	lang := getLang(r)
+
+	err := views.LoginPage(lang, loginCodes).Render(r.Context(), w)
+	if err != nil {
+		HxError(w, "could not render login page", http.StatusInternalServerError)
+		log.Println("error rendering login page:", err)
+		return
+	}
+}
+
+func RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	acc, err := accountStore.Register(r.FormValue("username"), r.FormValue("password"))
+	if err != nil {
+		HxError(w, err.Error(), http.StatusBadRequest)
+		log.Println("error registering account:", err)
+		return
+	}
+
+	err = startSession(w, acc)
+	if err != nil {
+		HxError(w, "could not log in", http.StatusInternalServerError)
+		log.Println("error starting session:", err)
+		return
+	}
+}
+
+func LoginHandler(w http.ResponseWriter, r *http.Request) {
+	ip := clientIP(r)
+	if !loginThrottle.Allowed(ip) {
+		HxError(w, "too many failed logins, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	acc, err := accountStore.Login(r.FormValue("username"), r.FormValue("password"))
+	if err != nil {
+		loginThrottle.Fail(ip)
+		HxError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	loginThrottle.Reset(ip)
+
+	err = startSession(w, acc)
+	if err != nil {
+		HxError(w, "could not log in", http.StatusInternalServerError)
+		log.Println("error starting session:", err)
+		return
+	}
+}
+
+// RequestCodeHandler prints a login code to the server log
+func RequestCodeHandler(w http.ResponseWriter, r *http.Request) {
+	if !loginCodes {
+		HxError(w, "login codes are disabled", http.StatusNotFound)
+		return
+	}
+
+	username := r.FormValue("username")
+	code, err := accountStore.RequestCode(username)
+	if err != nil {
+		HxError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("login code for %s: %s\n", username, code)
+
+	lang := getLang(r)
+
+	err = views.LoginCodeEntry(lang, username).Render(r.Context(), w)
+	if err != nil {
+		HxError(w, "could not render login code entry", http.StatusInternalServerError)
+		log.Println("error rendering login code entry:", err)
+		return
+	}
+}
+
+func CodeLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if !loginCodes {
+		HxError(w, "login codes are disabled", http.StatusNotFound)
+		return
+	}
+
+	ip := clientIP(r)
+	if !loginThrottle.Allowed(ip) {
+		HxError(w, "too many failed logins, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	acc, err := accountStore.LoginWithCode(r.FormValue("username"), r.FormValue("code"))
+	if errors.Is(err, accounts.ErrInvalidCode) {
+		loginThrottle.Fail(ip)
+		HxError(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		HxError(w, "could not log in", http.StatusInternalServerError)
+		log.Println("error logging in with code:", err)
+		return
+	}
+	loginThrottle.Reset(ip)
+
+	err = startSession(w, acc)
+	if err != nil {
+		HxError(w, "could not log in", http.StatusInternalServerError)
+		log.Println("error starting session:", err)
+		return
+	}
+}
+
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	if sessionCookie, err := r.Cookie("session"); err == nil {
+		if err := accountStore.EndSession(sessionCookie.Value); err != nil {
+			log.Println("error ending session:", err)
+		}
+	}
+	// the next player on this device gets a new anonymous id
+	for _, name := range []string{"session", "player_id"} {
+		http.SetCookie(w, &http.Cookie{Name: name, Path: "/", MaxAge: -1})
+	}
+	w.Header().Set("HX-Redirect", "/")
+}
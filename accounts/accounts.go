@@ -0,0 +1,281 @@
+// Package accounts keeps optional player accounts in a local bbolt database,
+// or in Redis when several instances share them, so that a person keeps their
+// history and rating across rooms.
+package accounts
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrUsernameTaken      = errors.New("username is taken")
+	ErrInvalidCredentials = errors.New("wrong username or password")
+	ErrInvalidCode        = errors.New("wrong or expired login code")
+	ErrNoSession          = errors.New("not logged in")
+)
+
+const (
+	sessionTTL = 30 * 24 * time.Hour
+	codeTTL    = 10 * time.Minute
+)
+
+var (
+	accountsBucket  = []byte("accounts")  // account id -> account
+	usernamesBucket = []byte("usernames") // lowercase username -> account id
+	sessionsBucket  = []byte("sessions")  // session token -> session
+	codesBucket     = []byte("codes")     // lowercase username -> pending login code
+)
+
+type Account struct {
+	ID           string
+	Username     string
+	PasswordHash []byte `json:",omitempty"` // empty for accounts created with a login code
+	CreatedAt    time.Time
+}
+
+type session struct {
+	AccountID string
+	Expires   time.Time
+}
+
+type loginCode struct {
+	Code    string
+	Expires time.Time
+}
+
+type Store struct {
+	db database
+}
+
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, b := range [][]byte{accountsBucket, usernamesBucket, sessionsBucket, codesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Store{db: boltDB{db}}, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func validUsername(username string) error {
+	n := utf8.RuneCountInString(username)
+	if n < 3 || n > 20 || strings.TrimSpace(username) != username {
+		return errors.New("username must have 3 to 20 characters")
+	}
+	return nil
+}
+
+// Register creates an account with a password
+func (s *Store) Register(username, password string) (*Account, error) {
+	if err := validUsername(username); err != nil {
+		return nil, err
+	}
+	if len(password) < 8 {
+		return nil, errors.New("password must have at least 8 characters")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	return s.create(username, hash)
+}
+
+func (s *Store) create(username string, hash []byte) (*Account, error) {
+	acc := &Account{
+		ID:           uuid.New().String(),
+		Username:     username,
+		PasswordHash: hash,
+		CreatedAt:    time.Now(),
+	}
+	err := s.db.Update(func(tx transaction) error {
+		names := tx.Bucket(usernamesBucket)
+		key := []byte(strings.ToLower(username))
+		if names.Get(key) != nil {
+			return ErrUsernameTaken
+		}
+		data, err := json.Marshal(acc)
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(accountsBucket).Put([]byte(acc.ID), data); err != nil {
+			return err
+		}
+		return names.Put(key, []byte(acc.ID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
+
+// Login checks the password of the account
+func (s *Store) Login(username, password string) (*Account, error) {
+	acc, err := s.ByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if acc == nil || len(acc.PasswordHash) == 0 {
+		return nil, ErrInvalidCredentials
+	}
+	if bcrypt.CompareHashAndPassword(acc.PasswordHash, []byte(password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return acc, nil
+}
+
+// RequestCode creates a one time login code for the username, the caller
+// decides how to deliver it (e.g. the server log for local setups)
+func (s *Store) RequestCode(username string) (string, error) {
+	if err := validUsername(username); err != nil {
+		return "", err
+	}
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := hex.EncodeToString(buf)
+	data, err := json.Marshal(loginCode{Code: code, Expires: time.Now().Add(codeTTL)})
+	if err != nil {
+		return "", err
+	}
+	err = s.db.Update(func(tx transaction) error {
+		return tx.Bucket(codesBucket).Put([]byte(strings.ToLower(username)), data)
+	})
+	return code, err
+}
+
+// LoginWithCode uses up the login code, the account is created on first login
+func (s *Store) LoginWithCode(username, code string) (*Account, error) {
+	key := []byte(strings.ToLower(username))
+	err := s.db.Update(func(tx transaction) error {
+		codes := tx.Bucket(codesBucket)
+		data := codes.Get(key)
+		if data == nil {
+			return ErrInvalidCode
+		}
+		var pending loginCode
+		if err := json.Unmarshal(data, &pending); err != nil {
+			return err
+		}
+		if subtle.ConstantTimeCompare([]byte(pending.Code), []byte(code)) != 1 || time.Now().After(pending.Expires) {
+			return ErrInvalidCode
+		}
+		return codes.Delete(key)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	acc, err := s.ByUsername(username)
+	if err != nil {
+		return nil, err
+	}
+	if acc != nil {
+		return acc, nil
+	}
+	return s.create(username, nil)
+}
+
+func (s *Store) Get(id string) (*Account, error) {
+	var acc *Account
+	err := s.db.View(func(tx transaction) error {
+		data := tx.Bucket(accountsBucket).Get([]byte(id))
+		if data == nil {
+			return nil
+		}
+		acc = &Account{}
+		return json.Unmarshal(data, acc)
+	})
+	return acc, err
+}
+
+// ByUsername returns nil if there is no such account
+func (s *Store) ByUsername(username string) (*Account, error) {
+	var id []byte
+	s.db.View(func(tx transaction) error {
+		id = tx.Bucket(usernamesBucket).Get([]byte(strings.ToLower(username)))
+		if id != nil {
+			id = append([]byte{}, id...)
+		}
+		return nil
+	})
+	if id == nil {
+		return nil, nil
+	}
+	return s.Get(string(id))
+}
+
+// NewSession starts a session for the account and returns its token
+func (s *Store) NewSession(accountID string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+	data, err := json.Marshal(session{AccountID: accountID, Expires: time.Now().Add(sessionTTL)})
+	if err != nil {
+		return "", err
+	}
+	err = s.db.Update(func(tx transaction) error {
+		return tx.Bucket(sessionsBucket).Put([]byte(token), data)
+	})
+	return token, err
+}
+
+// Session returns the account logged in with the token
+func (s *Store) Session(token string) (*Account, error) {
+	var sess session
+	err := s.db.View(func(tx transaction) error {
+		data := tx.Bucket(sessionsBucket).Get([]byte(token))
+		if data == nil {
+			return ErrNoSession
+		}
+		return json.Unmarshal(data, &sess)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(sess.Expires) {
+		s.EndSession(token)
+		return nil, ErrNoSession
+	}
+	acc, err := s.Get(sess.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	if acc == nil {
+		return nil, ErrNoSession
+	}
+	return acc, nil
+}
+
+func (s *Store) EndSession(token string) error {
+	return s.db.Update(func(tx transaction) error {
+		return tx.Bucket(sessionsBucket).Delete([]byte(token))
+	})
+}
@@ -0,0 +1,112 @@
+package accounts
+
+import (
+	bolt "go.etcd.io/bbolt"
+)
+
+// database is where the store keeps its buckets: bbolt for one instance (see
+// Open), Redis for several instances sharing the accounts (see OpenRedis).
+// Buckets hold keys in byte order and nest one level deep.
+type database interface {
+	// View runs fn in a read-only transaction
+	View(fn func(tx transaction) error) error
+	// Update runs fn in a read-write transaction, its changes are saved
+	// together when fn returns nil. fn may run more than once.
+	Update(fn func(tx transaction) error) error
+	Close() error
+}
+
+type transaction interface {
+	// Bucket returns the bucket, with bbolt nil when it was never created
+	Bucket(name []byte) bucket
+	CreateBucketIfNotExists(name []byte) (bucket, error)
+}
+
+type bucket interface {
+	// Get returns nil for a missing key, the value is only valid during the
+	// transaction
+	Get(key []byte) []byte
+	Put(key, value []byte) error
+	Delete(key []byte) error
+	// ForEach calls fn for every key in byte order
+	ForEach(fn func(key, value []byte) error) error
+	// Bucket returns the nested bucket, with bbolt nil when it was never created
+	Bucket(name []byte) bucket
+	CreateBucketIfNotExists(name []byte) (bucket, error)
+}
+
+type boltDB struct {
+	db *bolt.DB
+}
+
+func (d boltDB) View(fn func(tx transaction) error) error {
+	return d.db.View(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx})
+	})
+}
+
+func (d boltDB) Update(fn func(tx transaction) error) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return fn(boltTx{tx})
+	})
+}
+
+func (d boltDB) Close() error {
+	return d.db.Close()
+}
+
+type boltTx struct {
+	tx *bolt.Tx
+}
+
+func (t boltTx) Bucket(name []byte) bucket {
+	return boltBucketOrNil(t.tx.Bucket(name))
+}
+
+func (t boltTx) CreateBucketIfNotExists(name []byte) (bucket, error) {
+	b, err := t.tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+	return boltBucket{b}, nil
+}
+
+type boltBucket struct {
+	b *bolt.Bucket
+}
+
+func (b boltBucket) Get(key []byte) []byte {
+	return b.b.Get(key)
+}
+
+func (b boltBucket) Put(key, value []byte) error {
+	return b.b.Put(key, value)
+}
+
+func (b boltBucket) Delete(key []byte) error {
+	return b.b.Delete(key)
+}
+
+func (b boltBucket) ForEach(fn func(key, value []byte) error) error {
+	return b.b.ForEach(fn)
+}
+
+func (b boltBucket) Bucket(name []byte) bucket {
+	return boltBucketOrNil(b.b.Bucket(name))
+}
+
+func (b boltBucket) CreateBucketIfNotExists(name []byte) (bucket, error) {
+	nested, err := b.b.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+	return boltBucket{nested}, nil
+}
+
+// a missing bolt bucket is a nil interface, not an interface holding nil
+func boltBucketOrNil(b *bolt.Bucket) bucket {
+	if b == nil {
+		return nil
+	}
+	return boltBucket{b}
+}
@@ -0,0 +1,80 @@
+package accounts
+
+import (
+	"encoding/json"
+	"yamb/record"
+)
+
+var (
+	gamesBucket        = []byte("games")         // game key -> game record
+	accountGamesBucket = []byte("account_games") // account id -> bucket of game keys
+)
+
+// SaveGame keeps the record of a finished game for every account that played it
+func (s *Store) SaveGame(rec *record.Record) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	key := gameKey(rec)
+	return s.db.Update(func(tx transaction) error {
+		games, err := tx.CreateBucketIfNotExists(gamesBucket)
+		if err != nil {
+			return err
+		}
+		if err := games.Put(key, data); err != nil {
+			return err
+		}
+		accountGames, err := tx.CreateBucketIfNotExists(accountGamesBucket)
+		if err != nil {
+			return err
+		}
+		for _, p := range rec.Players {
+			if p.AccountID == "" {
+				continue
+			}
+			b, err := accountGames.CreateBucketIfNotExists([]byte(p.AccountID))
+			if err != nil {
+				return err
+			}
+			if err := b.Put(key, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// games are keyed by the time they finished so they iterate oldest first
+func gameKey(rec *record.Record) []byte {
+	return []byte(rec.FinishedAt.UTC().Format("20060102T150405.000000000") + "-" + rec.RoomID)
+}
+
+// Games returns the finished games of the account, oldest first
+func (s *Store) Games(accountID string) ([]*record.Record, error) {
+	recs := []*record.Record{}
+	err := s.db.View(func(tx transaction) error {
+		accountGames := tx.Bucket(accountGamesBucket)
+		if accountGames == nil {
+			return nil
+		}
+		b := accountGames.Bucket([]byte(accountID))
+		if b == nil {
+			return nil
+		}
+		games := tx.Bucket(gamesBucket)
+		return b.ForEach(func(key, _ []byte) error {
+			data := games.Get(key)
+			if data == nil {
+				return nil
+			}
+			rec := &record.Record{}
+			if err := json.Unmarshal(data, rec); err != nil {
+				return err
+			}
+			recs = append(recs, rec)
+			return nil
+		})
+	})
+	return recs, err
+}
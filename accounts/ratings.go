@@ -0,0 +1,210 @@
+package accounts
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+	"yamb/rating"
+	"yamb/record"
+)
+
+var (
+	ratingsBucket       = []byte("ratings")        // pool key -> bucket of account id -> rating
+	ratingHistoryBucket = []byte("rating_history") // account id -> bucket of game key -> rating change
+)
+
+type Rating struct {
+	Value float64
+	Games int
+}
+
+type PoolRating struct {
+	rating.Pool
+	Rating
+}
+
+type LeaderboardEntry struct {
+	Username string
+	Rating
+}
+
+// one rated game in the rating history of an account
+type RatingChange struct {
+	RoomID     string
+	Mode       string
+	Dice       int
+	Before     float64
+	After      float64
+	FinishedAt time.Time
+}
+
+func (c RatingChange) Delta() float64 {
+	return c.After - c.Before
+}
+
+// RateGame updates the ratings of the accounts that played a rated game,
+// rating the same game twice does nothing
+func (s *Store) RateGame(rec *record.Record) error {
+	if !rating.Rated(rec) {
+		return nil
+	}
+	key := gameKey(rec)
+	return s.db.Update(func(tx transaction) error {
+		pool, err := tx.CreateBucketIfNotExists(ratingsBucket)
+		if err != nil {
+			return err
+		}
+		pool, err = pool.CreateBucketIfNotExists([]byte(rating.PoolOf(rec).Key()))
+		if err != nil {
+			return err
+		}
+		history, err := tx.CreateBucketIfNotExists(ratingHistoryBucket)
+		if err != nil {
+			return err
+		}
+
+		current := map[string]float64{}
+		games := map[string]int{}
+		for _, p := range rec.Players {
+			if b := history.Bucket([]byte(p.AccountID)); b != nil && b.Get(key) != nil {
+				return nil
+			}
+			data := pool.Get([]byte(p.AccountID))
+			if data == nil {
+				continue
+			}
+			r := Rating{}
+			if err := json.Unmarshal(data, &r); err != nil {
+				return err
+			}
+			current[p.AccountID] = r.Value
+			games[p.AccountID] = r.Games
+		}
+
+		for _, c := range rating.Update(rec, current) {
+			data, err := json.Marshal(Rating{Value: c.After, Games: games[c.AccountID] + 1})
+			if err != nil {
+				return err
+			}
+			if err := pool.Put([]byte(c.AccountID), data); err != nil {
+				return err
+			}
+			data, err = json.Marshal(RatingChange{
+				RoomID:     rec.RoomID,
+				Mode:       rec.Mode,
+				Dice:       rec.Dice,
+				Before:     c.Before,
+				After:      c.After,
+				FinishedAt: rec.FinishedAt,
+			})
+			if err != nil {
+				return err
+			}
+			b, err := history.CreateBucketIfNotExists([]byte(c.AccountID))
+			if err != nil {
+				return err
+			}
+			if err := b.Put(key, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Leaderboard returns the rated accounts of the pool, highest rating first
+func (s *Store) Leaderboard(pool rating.Pool, limit int) ([]LeaderboardEntry, error) {
+	entries := []LeaderboardEntry{}
+	err := s.db.View(func(tx transaction) error {
+		ratings := tx.Bucket(ratingsBucket)
+		if ratings == nil {
+			return nil
+		}
+		b := ratings.Bucket([]byte(pool.Key()))
+		if b == nil {
+			return nil
+		}
+		accounts := tx.Bucket(accountsBucket)
+		return b.ForEach(func(id, data []byte) error {
+			entry := LeaderboardEntry{}
+			if err := json.Unmarshal(data, &entry.Rating); err != nil {
+				return err
+			}
+			acc := Account{}
+			if err := json.Unmarshal(accounts.Get(id), &acc); err != nil {
+				return err
+			}
+			entry.Username = acc.Username
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(entries, func(a, b LeaderboardEntry) int {
+		switch {
+		case a.Value > b.Value:
+			return -1
+		case a.Value < b.Value:
+			return 1
+		}
+		return b.Games - a.Games
+	})
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// Ratings returns the current ratings of the account in the pools it played
+func (s *Store) Ratings(accountID string) ([]PoolRating, error) {
+	ratings := []PoolRating{}
+	err := s.db.View(func(tx transaction) error {
+		b := tx.Bucket(ratingsBucket)
+		if b == nil {
+			return nil
+		}
+		for _, pool := range rating.Pools() {
+			poolBucket := b.Bucket([]byte(pool.Key()))
+			if poolBucket == nil {
+				continue
+			}
+			data := poolBucket.Get([]byte(accountID))
+			if data == nil {
+				continue
+			}
+			r := Rating{}
+			if err := json.Unmarshal(data, &r); err != nil {
+				return err
+			}
+			ratings = append(ratings, PoolRating{Pool: pool, Rating: r})
+		}
+		return nil
+	})
+	return ratings, err
+}
+
+// RatingHistory returns the rating changes of the account, oldest first
+func (s *Store) RatingHistory(accountID string) ([]RatingChange, error) {
+	changes := []RatingChange{}
+	err := s.db.View(func(tx transaction) error {
+		history := tx.Bucket(ratingHistoryBucket)
+		if history == nil {
+			return nil
+		}
+		b := history.Bucket([]byte(accountID))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(_, data []byte) error {
+			c := RatingChange{}
+			if err := json.Unmarshal(data, &c); err != nil {
+				return err
+			}
+			changes = append(changes, c)
+			return nil
+		})
+	})
+	return changes, err
+}
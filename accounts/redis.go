@@ -0,0 +1,196 @@
+package accounts
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"yamb/resp"
+)
+
+// every bucket is a Redis hash, nested ones are named after their parent
+const redisKeyPrefix = "yamb:accounts:"
+
+// transactions that keep meeting changes of other instances give up after
+const redisRetries = 10
+
+var errReadOnly = errors.New("read-only transaction")
+
+// OpenRedis keeps the accounts in Redis (or a server speaking its protocol),
+// so that every instance connected to it shares the accounts and sessions
+func OpenRedis(client *resp.Client) *Store {
+	return &Store{db: redisDB{client: client}}
+}
+
+type redisDB struct {
+	client *resp.Client
+}
+
+func (d redisDB) View(fn func(tx transaction) error) error {
+	tx := &redisTx{do: d.client.Do}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.err
+}
+
+// Update reads with the hashes it reads watched, so that the writes are only
+// made when nobody changed those hashes in the meantime, and tries again
+// otherwise
+func (d redisDB) Update(fn func(tx transaction) error) error {
+	for range redisRetries {
+		err := d.client.Watch(nil, func(conn *resp.Tx) ([][]string, error) {
+			tx := &redisTx{do: conn.Do, writable: true, watched: map[string]bool{}, writes: map[string]map[string]*string{}}
+			if err := fn(tx); err != nil {
+				return nil, err
+			}
+			if tx.err != nil {
+				return nil, tx.err
+			}
+			return tx.commands(), nil
+		})
+		if !errors.Is(err, resp.ErrAborted) {
+			return err
+		}
+	}
+	return errors.New("accounts keep changing, try again")
+}
+
+func (d redisDB) Close() error {
+	return d.client.Close()
+}
+
+type redisTx struct {
+	do       func(args ...string) (any, error)
+	writable bool
+	watched  map[string]bool               // hashes read so far
+	writes   map[string]map[string]*string // hash -> field -> value, nil deletes
+	err      error                         // first failed read, fails the transaction
+}
+
+func (t *redisTx) Bucket(name []byte) bucket {
+	return &redisBucket{tx: t, key: redisKeyPrefix + string(name)}
+}
+
+func (t *redisTx) CreateBucketIfNotExists(name []byte) (bucket, error) {
+	if !t.writable {
+		return nil, errReadOnly
+	}
+	return t.Bucket(name), nil
+}
+
+// read runs a read command on the hash, watching it first in an update
+func (t *redisTx) read(key string, args ...string) any {
+	if t.err != nil {
+		return nil
+	}
+	if t.watched != nil && !t.watched[key] {
+		if _, err := t.do("WATCH", key); err != nil {
+			t.err = err
+			return nil
+		}
+		t.watched[key] = true
+	}
+	reply, err := t.do(args...)
+	if err != nil {
+		t.err = err
+	}
+	return reply
+}
+
+func (t *redisTx) write(key, field string, value *string) error {
+	if !t.writable {
+		return errReadOnly
+	}
+	if t.writes[key] == nil {
+		t.writes[key] = map[string]*string{}
+	}
+	t.writes[key][field] = value
+	return nil
+}
+
+// commands turns the writes into HSET and HDEL commands
+func (t *redisTx) commands() [][]string {
+	cmds := [][]string{}
+	for _, key := range slices.Sorted(maps.Keys(t.writes)) {
+		set, del := []string{"HSET", key}, []string{"HDEL", key}
+		for _, field := range slices.Sorted(maps.Keys(t.writes[key])) {
+			if value := t.writes[key][field]; value != nil {
+				set = append(set, field, *value)
+			} else {
+				del = append(del, field)
+			}
+		}
+		if len(set) > 2 {
+			cmds = append(cmds, set)
+		}
+		if len(del) > 2 {
+			cmds = append(cmds, del)
+		}
+	}
+	return cmds
+}
+
+type redisBucket struct {
+	tx  *redisTx
+	key string
+}
+
+func (b *redisBucket) Get(key []byte) []byte {
+	if value, ok := b.tx.writes[b.key][string(key)]; ok {
+		if value == nil {
+			return nil
+		}
+		return []byte(*value)
+	}
+	value, ok := b.tx.read(b.key, "HGET", b.key, string(key)).(string)
+	if !ok {
+		return nil
+	}
+	return []byte(value)
+}
+
+func (b *redisBucket) Put(key, value []byte) error {
+	v := string(value)
+	return b.tx.write(b.key, string(key), &v)
+}
+
+func (b *redisBucket) Delete(key []byte) error {
+	return b.tx.write(b.key, string(key), nil)
+}
+
+func (b *redisBucket) ForEach(fn func(key, value []byte) error) error {
+	values := map[string]string{}
+	reply, _ := b.tx.read(b.key, "HGETALL", b.key).([]any)
+	for i := 0; i+1 < len(reply); i += 2 {
+		field, _ := reply[i].(string)
+		value, _ := reply[i+1].(string)
+		values[field] = value
+	}
+	for field, value := range b.tx.writes[b.key] {
+		if value == nil {
+			delete(values, field)
+		} else {
+			values[field] = *value
+		}
+	}
+	if b.tx.err != nil {
+		return b.tx.err
+	}
+	for _, field := range slices.Sorted(maps.Keys(values)) {
+		if err := fn([]byte(field), []byte(values[field])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *redisBucket) Bucket(name []byte) bucket {
+	return &redisBucket{tx: b.tx, key: b.key + ":" + string(name)}
+}
+
+func (b *redisBucket) CreateBucketIfNotExists(name []byte) (bucket, error) {
+	if !b.tx.writable {
+		return nil, errReadOnly
+	}
+	return b.Bucket(name), nil
+}
@@ -0,0 +1,168 @@
+package accounts
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+	"yamb/record"
+)
+
+// solo games are kept by owner, the account of the player or, for players
+// without one, their browser (see soloOwner), so everyone has personal bests
+
+var (
+	soloBestsBucket   = []byte("solo_bests")   // owner -> bucket of rules-dice -> solo scores
+	soloResultsBucket = []byte("solo_results") // owner -> bucket of room id -> result of the last game
+)
+
+// entries of a personal best list
+const soloBestLength = 10
+
+type SoloScore struct {
+	RoomID     string
+	Total      int
+	FinishedAt time.Time
+}
+
+// SoloScores are the solo games of a player with one ruleset and dice count
+type SoloScores struct {
+	Attempts int
+	Sum      int         // of every total, for the average
+	Best     []SoloScore // highest first, at most soloBestLength
+}
+
+func (s SoloScores) Average() float64 {
+	if s.Attempts == 0 {
+		return 0
+	}
+	return float64(s.Sum) / float64(s.Attempts)
+}
+
+// SoloResult compares a solo game to the attempts before it
+type SoloResult struct {
+	Game     string // game key, saving the same game twice does nothing
+	Rules    string
+	Dice     int
+	Total    int
+	Rank     int         // place on the best list, 0 when not on it
+	Previous SoloScores  // before this game
+	Best     []SoloScore // after this game
+}
+
+// PreviousBest is the best total before this game, 0 for the first attempt
+func (r SoloResult) PreviousBest() int {
+	if len(r.Previous.Best) == 0 {
+		return 0
+	}
+	return r.Previous.Best[0].Total
+}
+
+func soloKey(rules string, dice int) []byte {
+	return fmt.Appendf(nil, "%s-%d", rules, dice)
+}
+
+// SaveSolo adds a finished solo game to the personal bests of the owner and
+// keeps how it compared to the earlier ones
+func (s *Store) SaveSolo(owner string, rec *record.Record) error {
+	if len(rec.Players) != 1 {
+		return fmt.Errorf("solo game with %d players", len(rec.Players))
+	}
+	if owner == "" {
+		return fmt.Errorf("solo game without owner")
+	}
+	p := rec.Players[0]
+	key := string(gameKey(rec))
+
+	return s.db.Update(func(tx transaction) error {
+		result := &SoloResult{Game: key, Rules: rec.Rules, Dice: rec.Dice, Total: p.Total}
+		results, err := playerBucket(tx, soloResultsBucket, owner)
+		if err != nil {
+			return err
+		}
+		if data := results.Get([]byte(rec.RoomID)); data != nil {
+			saved := &SoloResult{}
+			if err := json.Unmarshal(data, saved); err != nil {
+				return err
+			}
+			if saved.Game == key {
+				return nil
+			}
+		}
+
+		bests, err := playerBucket(tx, soloBestsBucket, owner)
+		if err != nil {
+			return err
+		}
+		scores := SoloScores{}
+		if data := bests.Get(soloKey(rec.Rules, rec.Dice)); data != nil {
+			if err := json.Unmarshal(data, &scores); err != nil {
+				return err
+			}
+		}
+		result.Previous = scores
+		result.Previous.Best = slices.Clone(scores.Best)
+
+		// equal totals keep the older game first
+		score := SoloScore{RoomID: rec.RoomID, Total: p.Total, FinishedAt: rec.FinishedAt}
+		idx, _ := slices.BinarySearchFunc(scores.Best, score.Total, func(s SoloScore, total int) int {
+			if s.Total >= total {
+				return -1
+			}
+			return 1
+		})
+		if idx < soloBestLength {
+			scores.Best = slices.Insert(scores.Best, idx, score)
+			scores.Best = scores.Best[:min(len(scores.Best), soloBestLength)]
+			result.Rank = idx + 1
+		}
+		scores.Attempts++
+		scores.Sum += p.Total
+		result.Best = scores.Best
+
+		data, err := json.Marshal(scores)
+		if err != nil {
+			return err
+		}
+		if err := bests.Put(soloKey(rec.Rules, rec.Dice), data); err != nil {
+			return err
+		}
+		data, err = json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		return results.Put([]byte(rec.RoomID), data)
+	})
+}
+
+// SoloResult returns the result of the last solo game of the owner in the
+// room, nil when there is none
+func (s *Store) SoloResult(owner, roomID string) (*SoloResult, error) {
+	var result *SoloResult
+	err := s.db.View(func(tx transaction) error {
+		b := tx.Bucket(soloResultsBucket)
+		if b == nil {
+			return nil
+		}
+		if b = b.Bucket([]byte(owner)); b == nil {
+			return nil
+		}
+		data := b.Get([]byte(roomID))
+		if data == nil {
+			return nil
+		}
+		result = &SoloResult{}
+		return json.Unmarshal(data, result)
+	})
+	return result, err
+}
+
+// playerBucket returns the bucket of the owner inside the named bucket,
+// creating both when needed
+func playerBucket(tx transaction, name []byte, owner string) (bucket, error) {
+	b, err := tx.CreateBucketIfNotExists(name)
+	if err != nil {
+		return nil, err
+	}
+	return b.CreateBucketIfNotExists([]byte(owner))
+}
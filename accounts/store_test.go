@@ -0,0 +1,171 @@
+package accounts
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+	"yamb/rating"
+	"yamb/record"
+	"yamb/resp"
+	"yamb/resp/resptest"
+)
+
+// every backend returns two instances of the store, with redis they share the
+// accounts through the server, with bbolt there is only one
+var backends = []struct {
+	name string
+	open func(t *testing.T) (*Store, *Store)
+}{
+	{"bbolt", func(t *testing.T) (*Store, *Store) {
+		s, err := Open(filepath.Join(t.TempDir(), "accounts.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { s.Close() })
+		return s, s
+	}},
+	{"redis", func(t *testing.T) (*Store, *Store) {
+		srv := resptest.NewServer(t, "")
+		instances := make([]*Store, 2)
+		for i := range instances {
+			client, err := resp.Dial(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			instances[i] = OpenRedis(client)
+			t.Cleanup(func() { instances[i].Close() })
+		}
+		return instances[0], instances[1]
+	}},
+}
+
+func TestAccounts(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			a, b := backend.open(t)
+
+			alice, err := a.Register("alice", "password1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := b.Register("Alice", "password2"); !errors.Is(err, ErrUsernameTaken) {
+				t.Errorf("Register of a taken name = %v, want %v", err, ErrUsernameTaken)
+			}
+			if acc, err := b.Login("alice", "password1"); err != nil || acc.ID != alice.ID {
+				t.Errorf("Login = %v, %v", acc, err)
+			}
+			if _, err := b.Login("alice", "password2"); !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("Login with a wrong password = %v, want %v", err, ErrInvalidCredentials)
+			}
+
+			// the code is requested on one instance and used on the other
+			code, err := a.RequestCode("bob")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := b.LoginWithCode("bob", "wrong"); !errors.Is(err, ErrInvalidCode) {
+				t.Errorf("LoginWithCode with a wrong code = %v, want %v", err, ErrInvalidCode)
+			}
+			bob, err := b.LoginWithCode("bob", code)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := a.LoginWithCode("bob", code); !errors.Is(err, ErrInvalidCode) {
+				t.Errorf("second LoginWithCode = %v, want %v", err, ErrInvalidCode)
+			}
+
+			token, err := a.NewSession(alice.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if acc, err := b.Session(token); err != nil || acc.ID != alice.ID {
+				t.Errorf("Session = %v, %v", acc, err)
+			}
+			if err := b.EndSession(token); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := a.Session(token); !errors.Is(err, ErrNoSession) {
+				t.Errorf("Session after EndSession = %v, want %v", err, ErrNoSession)
+			}
+
+			rec := &record.Record{
+				RoomID: "123456", Mode: "1v1", Dice: 6, FinishedAt: time.Now(),
+				Players: []record.PlayerRecord{
+					{ID: "p1", Username: "alice", AccountID: alice.ID, Team: 0, Rank: 1, Total: 700},
+					{ID: "p2", Username: "bob", AccountID: bob.ID, Team: 1, Rank: 2, Total: 600},
+				},
+			}
+			if err := a.SaveGame(rec); err != nil {
+				t.Fatal(err)
+			}
+			// rating the same game on another instance does nothing
+			for _, s := range []*Store{a, b} {
+				if err := s.RateGame(rec); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if games, err := b.Games(bob.ID); err != nil || len(games) != 1 || games[0].RoomID != rec.RoomID {
+				t.Errorf("Games = %v, %v", games, err)
+			}
+			board, err := b.Leaderboard(rating.PoolOf(rec), 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(board) != 2 || board[0].Username != "alice" || board[0].Games != 1 {
+				t.Errorf("Leaderboard = %+v", board)
+			}
+			if history, err := a.RatingHistory(bob.ID); err != nil || len(history) != 1 || history[0].Delta() >= 0 {
+				t.Errorf("RatingHistory = %+v, %v", history, err)
+			}
+
+			solo := &record.Record{
+				RoomID: "654321", Mode: "solo", Rules: "classic", Dice: 6, FinishedAt: time.Now(),
+				Players: []record.PlayerRecord{{ID: "p1", Username: "alice", Total: 800}},
+			}
+			for _, s := range []*Store{a, b} {
+				if err := s.SaveSolo("account:"+alice.ID, solo); err != nil {
+					t.Fatal(err)
+				}
+			}
+			result, err := b.SoloResult("account:"+alice.ID, solo.RoomID)
+			if err != nil || result == nil {
+				t.Fatalf("SoloResult = %v, %v", result, err)
+			}
+			if result.Rank != 1 || result.Previous.Attempts != 0 || len(result.Best) != 1 {
+				t.Errorf("the solo game counted twice: %+v", result)
+			}
+		})
+	}
+}
+
+// registrations of one name at the same time on both instances, only one of
+// them gets the name
+func TestRegisterRace(t *testing.T) {
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			a, b := backend.open(t)
+			errs := make(chan error)
+			for i := range 6 {
+				s := []*Store{a, b}[i%2]
+				go func() {
+					_, err := s.Register("carol", "password1")
+					errs <- err
+				}()
+			}
+			registered := 0
+			for range 6 {
+				err := <-errs
+				switch {
+				case err == nil:
+					registered++
+				case !errors.Is(err, ErrUsernameTaken):
+					t.Error(err)
+				}
+			}
+			if registered != 1 {
+				t.Errorf("%d accounts named carol", registered)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+// Package api holds the request and response types of the JSON API (/api/v1),
+// kept free of game types so that the schema only changes on purpose
+package api
+
+import "time"
+
+// every failed request answers with an error
+type Error struct {
+	Error string `json:"error"`
+}
+
+type CreateRoomRequest struct {
+	Mode       string `json:"mode"`                 // 1v1, 1v1v1, 2v2 or solo
+	Dice       int    `json:"dice"`                 // 5 or 6
+	Rules      string `json:"rules"`                // classic or extended
+	TurnOrder  string `json:"turn_order,omitempty"` // join, random, rolloff or loser
+	Passphrase string `json:"passphrase,omitempty"` // makes the room private
+}
+
+// the creator hosts the room, they still have to join it to play
+// keep the token secret, others know the player by the id
+type CreateRoomResponse struct {
+	RoomID      string `json:"room_id"`
+	PlayerToken string `json:"player_token"`
+	PlayerID    string `json:"player_id"`
+}
+
+type JoinRequest struct {
+	Username   string `json:"username"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+type JoinResponse struct {
+	PlayerToken string `json:"player_token"`
+	PlayerID    string `json:"player_id"`
+	Room        Room   `json:"room"`
+}
+
+type ReadyRequest struct {
+	Ready bool `json:"ready"`
+}
+
+type HoldRequest struct {
+	Die  int  `json:"die"` // index of the die
+	Held bool `json:"held"`
+}
+
+type SelectRequest struct {
+	Row string `json:"row"` // empty row and col unselect the cell
+	Col string `json:"col"`
+}
+
+type WriteResponse struct {
+	Score int  `json:"score"`
+	Room  Room `json:"room"`
+}
+
+type ChatRequest struct {
+	Message string `json:"message"`
+}
+
+type ChatMessage struct {
+	PlayerID string    `json:"player_id"`
+	Username string    `json:"username"`
+	Message  string    `json:"message"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+// state of a room, players are in turn order once the game started
+type Room struct {
+	ID              string   `json:"id"`
+	State           string   `json:"state"`
+	Mode            string   `json:"mode"`
+	Dice            int      `json:"dice"`
+	Rules           string   `json:"rules"`
+	TurnOrder       string   `json:"turn_order"`
+	Private         bool     `json:"private"`
+	HostID          string   `json:"host_id"`
+	CurrentPlayerID string   `json:"current_player_id,omitempty"`
+	Rows            []string `json:"rows"`    // row ids of the scorecards, top to bottom
+	Columns         []string `json:"columns"` // column ids, left to right
+	Roll            Roll     `json:"roll"`
+	Players         []Player `json:"players"`
+}
+
+// dice of the player on turn
+type Roll struct {
+	Values    []int  `json:"values"`
+	Held      []bool `json:"held"`
+	RollsLeft int    `json:"rolls_left"`
+}
+
+type Player struct {
+	ID        string                     `json:"id"`
+	Username  string                     `json:"username"`
+	Team      int                        `json:"team"`
+	Ready     bool                       `json:"ready"`
+	Total     int                        `json:"total"`
+	Scores    map[string]map[string]*int `json:"scores"` // row -> column -> score, null when empty
+	Selected  *Cell                      `json:"selected,omitempty"`
+	Announced bool                       `json:"announced"`
+}
+
+type Cell struct {
+	Row string `json:"row"`
+	Col string `json:"col"`
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version of the API, changes when the schema changes
+const Version = "1.0.0"
+
+// BasePath is where the operations are served
+const BasePath = "/api/v1"
+
+// Schema is the subset of OpenAPI 3.0 schemas the API types need
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// OpenAPI returns the OpenAPI 3 document of the operations, the schemas are
+// generated from the Go types by reflection. Types without a JSON schema
+// (channels, funcs, ...) panic, the document is broken then anyway
+func OpenAPI(ops []Operation) (map[string]any, error) {
+	g := &generator{schemas: make(map[string]*Schema)}
+	errorSchema := g.schema(reflect.TypeFor[Error]())
+
+	paths := make(map[string]map[string]any)
+	ids := make(map[string]bool)
+	for _, op := range ops {
+		if op.ID == "" || ids[op.ID] {
+			return nil, fmt.Errorf("%s %s: missing or duplicate operation id %q", op.Method, op.Path, op.ID)
+		}
+		ids[op.ID] = true
+		if op.Reply == nil || op.Status == 0 {
+			return nil, fmt.Errorf("%s: no response", op.ID)
+		}
+		path := BasePath + op.Path
+		if paths[path] == nil {
+			paths[path] = make(map[string]any)
+		}
+		method := strings.ToLower(op.Method)
+		if _, ok := paths[path][method]; ok {
+			return nil, fmt.Errorf("%s %s defined twice", op.Method, path)
+		}
+
+		responses := map[string]any{
+			"default": map[string]any{
+				"description": "error",
+				"content":     jsonContent(errorSchema),
+			},
+		}
+		reply := g.schema(reflect.TypeOf(op.Reply))
+		for _, status := range append([]int{op.Status}, op.Also...) {
+			responses[strconv.Itoa(status)] = map[string]any{
+				"description": "success",
+				"content":     jsonContent(reply),
+			}
+		}
+		operation := map[string]any{
+			"operationId": op.ID,
+			"summary":     op.Summary,
+			"responses":   responses,
+		}
+		if params := pathParams(op.Path); len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if op.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  jsonContent(g.schema(reflect.TypeOf(op.Request))),
+			}
+		}
+		if op.Auth {
+			operation["security"] = []map[string][]string{{"playerToken": {}}}
+		}
+		paths[path][method] = operation
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "yamb",
+			"version": Version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": g.schemas,
+			"securitySchemes": map[string]any{
+				"playerToken": map[string]any{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "player token returned when creating or joining a room",
+				},
+			},
+		},
+	}, nil
+}
+
+func jsonContent(schema *Schema) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": schema}}
+}
+
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+func pathParams(path string) []map[string]any {
+	var params []map[string]any
+	for _, m := range pathParam.FindAllStringSubmatch(path, -1) {
+		params = append(params, map[string]any{
+			"name":     m[1],
+			"in":       "path",
+			"required": true,
+			"schema":   &Schema{Type: "string"},
+		})
+	}
+	return params
+}
+
+// generator collects the named struct types as components
+type generator struct {
+	schemas map[string]*Schema
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Pointer:
+		s := *g.schema(t.Elem())
+		if s.Ref != "" {
+			// siblings of $ref are ignored in OpenAPI 3.0
+			return &Schema{Ref: s.Ref}
+		}
+		s.Nullable = true
+		return &s
+	case t.Kind() == reflect.Struct:
+		ref := "#/components/schemas/" + t.Name()
+		if _, ok := g.schemas[t.Name()]; !ok {
+			// registered before the fields so that recursive types terminate
+			s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+			g.schemas[t.Name()] = s
+			g.fields(s, t)
+		}
+		return &Schema{Ref: ref}
+	case t.Kind() == reflect.Slice:
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case t.Kind() == reflect.String:
+		return &Schema{Type: "string"}
+	case t.Kind() == reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return &Schema{Type: "number"}
+	}
+	panic("api: no schema for " + t.String())
+}
+
+// fields adds the json fields of the struct, fields without omitempty are required
+func (g *generator) fields(s *Schema, t reflect.Type) {
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		s.Properties[name] = g.schema(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
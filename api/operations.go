@@ -0,0 +1,80 @@
+package api
+
+import "net/http"
+
+// Operation is an endpoint of the API, the routes and the OpenAPI document
+// are both built from Operations so that they cannot drift apart
+type Operation struct {
+	ID      string // operationId, also names the handler
+	Method  string
+	Path    string // relative to /api/v1, chi style parameters ({roomID})
+	Summary string
+	Auth    bool  // needs the player token
+	Request any   // body type, nil when there is none
+	Status  int   // status of a successful response
+	Also    []int // other successful statuses with the same reply
+	Reply   any   // response body type
+}
+
+var Operations = []Operation{
+	{
+		ID: "createRoom", Method: http.MethodPost, Path: "/rooms",
+		Summary: "Create a room, the caller hosts it",
+		Request: CreateRoomRequest{}, Status: http.StatusCreated, Reply: CreateRoomResponse{},
+	},
+	{
+		ID: "getRoom", Method: http.MethodGet, Path: "/rooms/{roomID}",
+		Summary: "State of the room, private rooms only for their players",
+		Status:  http.StatusOK, Reply: Room{},
+	},
+	{
+		ID: "joinRoom", Method: http.MethodPost, Path: "/rooms/{roomID}/join",
+		Summary: "Join a room waiting for players, 200 when the player already sits in it",
+		Request: JoinRequest{}, Status: http.StatusCreated, Also: []int{http.StatusOK}, Reply: JoinResponse{},
+	},
+	{
+		ID: "setReady", Method: http.MethodPost, Path: "/rooms/{roomID}/ready",
+		Summary: "Mark the player (not) ready to start", Auth: true,
+		Request: ReadyRequest{}, Status: http.StatusOK, Reply: Room{},
+	},
+	{
+		ID: "startGame", Method: http.MethodPost, Path: "/rooms/{roomID}/start",
+		Summary: "Start the game, host only", Auth: true,
+		Status: http.StatusOK, Reply: Room{},
+	},
+	{
+		ID: "rollDice", Method: http.MethodPost, Path: "/rooms/{roomID}/roll",
+		Summary: "Roll the dice that are not held", Auth: true,
+		Status: http.StatusOK, Reply: Room{},
+	},
+	{
+		ID: "holdDie", Method: http.MethodPost, Path: "/rooms/{roomID}/hold",
+		Summary: "Keep or release a die", Auth: true,
+		Request: HoldRequest{}, Status: http.StatusOK, Reply: Room{},
+	},
+	{
+		ID: "selectCell", Method: http.MethodPost, Path: "/rooms/{roomID}/select",
+		Summary: "Select the cell to write to", Auth: true,
+		Request: SelectRequest{}, Status: http.StatusOK, Reply: Room{},
+	},
+	{
+		ID: "announce", Method: http.MethodPost, Path: "/rooms/{roomID}/announce",
+		Summary: "Announce the selected cell", Auth: true,
+		Status: http.StatusOK, Reply: Room{},
+	},
+	{
+		ID: "writeScore", Method: http.MethodPost, Path: "/rooms/{roomID}/write",
+		Summary: "Write the dice to the selected cell, ends the turn", Auth: true,
+		Status: http.StatusOK, Reply: WriteResponse{},
+	},
+	{
+		ID: "getChat", Method: http.MethodGet, Path: "/rooms/{roomID}/chat",
+		Summary: "Chat history of the room",
+		Status:  http.StatusOK, Reply: []ChatMessage{},
+	},
+	{
+		ID: "sendChat", Method: http.MethodPost, Path: "/rooms/{roomID}/chat",
+		Summary: "Send a chat message", Auth: true,
+		Request: ChatRequest{}, Status: http.StatusCreated, Reply: ChatMessage{},
+	},
+}
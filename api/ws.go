@@ -0,0 +1,51 @@
+package api
+
+import "encoding/json"
+
+// messages of the player websocket (/api/v1/rooms/{roomID}/ws), one JSON
+// object per websocket message.
+//
+// The client sends requests, Type is an operation id of Operations that
+// needs the player token (e.g. rollDice) or getRoom/getChat, Data is the
+// request body of the operation. Every request is answered with an ack
+// carrying the reply of the operation, or an error, with the id of the
+// request. Events of the room (the same as on the event stream, including
+// chat and presence) arrive as event messages in between.
+//
+// Clients that cannot set the Authorization header (browsers) send the
+// player token in an auth request first, tokens in the url would end up in
+// access logs.
+
+// ClientMessage is a request of the client
+type ClientMessage struct {
+	ID   string          `json:"id"`             // chosen by the client, echoed in the answer
+	Type string          `json:"type"`           // operation id
+	Data json.RawMessage `json:"data,omitempty"` // request body of the operation
+}
+
+// MessageAuth is the type of the request carrying the token, it must be the
+// first message of the client and is answered with an ack
+const MessageAuth = "auth"
+
+type AuthRequest struct {
+	Token string `json:"token"`
+}
+
+// types of the server messages
+const (
+	MessageState = "state" // full room state, first message after connecting
+	MessageAck   = "ack"   // request done, Data is the reply of the operation
+	MessageError = "error" // request failed, or the client sent garbage (no id)
+	MessageEvent = "event" // event of the room, Data is its payload
+)
+
+// ServerMessage is a message of the server
+type ServerMessage struct {
+	Type    string `json:"type"`
+	ID      string `json:"id,omitempty"`       // id of the request (ack and error)
+	Event   string `json:"event,omitempty"`    // event name (event)
+	EventID string `json:"event_id,omitempty"` // resume with ?last_event_id= after reconnecting
+	Status  int    `json:"status,omitempty"`   // http status code of the error
+	Error   string `json:"error,omitempty"`
+	Data    any    `json:"data,omitempty"`
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"yamb/api"
+	"yamb/broadcaster"
+	"yamb/game"
+)
+
+// apiAction is something a player does in their room, shared by the JSON API
+// and the websocket. body is the JSON request (may be empty), the reply is
+// sent back as JSON
+type apiAction func(room *game.Room, playerID string, body []byte) (any, error)
+
+// actions by operation id of api.Operations
+var apiActions = map[string]apiAction{
+	"setReady":   readyAction,
+	"startGame":  startAction,
+	"rollDice":   rollAction,
+	"holdDie":    holdAction,
+	"selectCell": selectAction,
+	"announce":   announceAction,
+	"writeScore": writeAction,
+	"sendChat":   chatAction,
+}
+
+// statusError is an error of an action with the status code to answer with
+type statusError struct {
+	status int
+	msg    string
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+// errorStatus maps errors of actions to http status codes, for the web
+// client and the API alike: errors made with failed carry their own, the
+// game's errors about who may act and when are 403 and 409, other broken
+// rules 400
+func errorStatus(err error) int {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.status
+	}
+	if errors.Is(err, game.ErrNotInRoom) || errors.Is(err, game.ErrNotYourTurn) {
+		return http.StatusForbidden
+	}
+	if errors.Is(err, game.ErrNotPlaying) || errors.Is(err, game.ErrNotInLobby) || errors.Is(err, game.ErrPaused) {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func failed(status int, format string, args ...any) error {
+	return &statusError{status: status, msg: fmt.Sprintf(format, args...)}
+}
+
+// decodeBody decodes the request of an action, an empty body leaves v as it is
+func decodeBody(body []byte, v any) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return failed(http.StatusBadRequest, "bad request body: %v", err)
+	}
+	return nil
+}
+
+func readyAction(room *game.Room, playerID string, body []byte) (any, error) {
+	var req api.ReadyRequest
+	if err := decodeBody(body, &req); err != nil {
+		return nil, err
+	}
+
+	if err := room.SetReady(playerID, req.Ready); err != nil {
+		return nil, failed(errorStatus(err), "could not change ready status: %v", err)
+	}
+
+	if err := saveRoom(room); err != nil {
+		return nil, err
+	}
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.PlayerReady, Data: room.LobbyPayload()})
+
+	return room.APIRoom(), nil
+}
+
+func startAction(room *game.Room, playerID string, body []byte) (any, error) {
+	if !room.IsHost(playerID) {
+		return nil, failed(http.StatusForbidden, "only the host can do this")
+	}
+
+	if err := room.Start(playerID); err != nil {
+		return nil, failed(http.StatusConflict, "could not start game: %v", err)
+	}
+
+	if err := saveRoom(room); err != nil {
+		return nil, err
+	}
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.GameStarted, Data: room.LobbyPayload()})
+	notifyTurn(room)
+
+	return room.APIRoom(), nil
+}
+
+func rollAction(room *game.Room, playerID string, body []byte) (any, error) {
+	if err := room.RollDice(playerID); err != nil {
+		return nil, failed(errorStatus(err), "could not roll dice: %v", err)
+	}
+
+	if err := diceChanged(room); err != nil {
+		return nil, err
+	}
+	return room.APIRoom(), nil
+}
+
+// diceChanged saves the room and shows everyone the new dice
+func diceChanged(room *game.Room) error {
+	if err := saveRoom(room); err != nil {
+		return err
+	}
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.DiceAreaUpdated, Data: room.DicePayload()})
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.ScoreUpdated, Data: room.ScoresPayload()})
+	return nil
+}
+
+// holdAction keeps or releases a die, unlike the web client it sets the
+// state instead of toggling it so that retries are harmless
+func holdAction(room *game.Room, playerID string, body []byte) (any, error) {
+	var req api.HoldRequest
+	if err := decodeBody(body, &req); err != nil {
+		return nil, err
+	}
+
+	changed, err := room.SetHeld(playerID, req.Die, req.Held)
+	if err != nil {
+		return nil, failed(errorStatus(err), "could not toggle die: %v", err)
+	}
+	if changed {
+		if err := diceChanged(room); err != nil {
+			return nil, err
+		}
+	}
+	return room.APIRoom(), nil
+}
+
+// toggleAction keeps a released die or releases a kept one, the web client
+// toggles the die that was clicked. It is no operation of the API.
+func toggleAction(room *game.Room, playerID string, body []byte) (any, error) {
+	var req api.HoldRequest
+	if err := decodeBody(body, &req); err != nil {
+		return nil, err
+	}
+
+	if err := room.ToggleDie(playerID, req.Die); err != nil {
+		return nil, failed(errorStatus(err), "could not toggle die: %v", err)
+	}
+
+	if err := diceChanged(room); err != nil {
+		return nil, err
+	}
+	return room.APIRoom(), nil
+}
+
+func selectAction(room *game.Room, playerID string, body []byte) (any, error) {
+	var req api.SelectRequest
+	if err := decodeBody(body, &req); err != nil {
+		return nil, err
+	}
+
+	if err := room.SelectCell(playerID, req.Row, req.Col); err != nil {
+		return nil, failed(errorStatus(err), "could not select cell: %v", err)
+	}
+
+	if err := saveRoom(room); err != nil {
+		return nil, err
+	}
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.CellSelected, Data: room.CellPayload(playerID)})
+
+	return room.APIRoom(), nil
+}
+
+func announceAction(room *game.Room, playerID string, body []byte) (any, error) {
+	if err := room.Announce(playerID); err != nil {
+		return nil, failed(errorStatus(err), "could not announce: %v", err)
+	}
+
+	if err := saveRoom(room); err != nil {
+		return nil, err
+	}
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.ScoreAnnounced, Data: room.CellPayload(playerID)})
+
+	return room.APIRoom(), nil
+}
+
+func writeAction(room *game.Room, playerID string, body []byte) (any, error) {
+	score, err := room.WriteScore(playerID)
+	if err != nil {
+		return nil, failed(errorStatus(err), "could not fill cell: %v", err)
+	}
+
+	if err := saveRoom(room); err != nil {
+		return nil, err
+	}
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.TurnEnded, Data: room.TurnPayload()})
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.ScoreUpdated, Data: room.ScoresPayload()})
+	notifyTurn(room)
+
+	if room.GetState() == game.StateFinished {
+		room.SortPlayersByScore()
+		if err := saveRoom(room); err != nil {
+			return nil, err
+		}
+		archiveGame(room)
+		room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.GameEnded, Data: room.ScoresPayload()})
+	}
+
+	return api.WriteResponse{Score: score, Room: room.APIRoom()}, nil
+}
+
+func chatAction(room *game.Room, playerID string, body []byte) (any, error) {
+	var req api.ChatRequest
+	if err := decodeBody(body, &req); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(req.Message) == "" {
+		return nil, failed(http.StatusBadRequest, "message is required")
+	}
+
+	player := room.GetPlayerByID(playerID)
+	if player == nil {
+		return nil, failed(http.StatusForbidden, "%v", game.ErrNotInRoom)
+	}
+	chatMsg := game.NewChatMessage(playerID, req.Message)
+	room.Mu.Lock()
+	room.ChatHistory = append(room.ChatHistory, chatMsg)
+	room.Mu.Unlock()
+	if err := saveRoom(room); err != nil {
+		return nil, err
+	}
+
+	msg := api.ChatMessage{
+		PlayerID: playerID,
+		Username: player.Username,
+		Message:  chatMsg.Message,
+		SentAt:   chatMsg.SentAt,
+	}
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.ChatMessage, Data: broadcaster.ChatPayload(msg)})
+
+	return msg, nil
+}
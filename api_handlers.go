@@ -0,0 +1,296 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"yamb/api"
+	"yamb/broadcaster"
+	"yamb/game"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// JSON API (/api/v1) for scripts and other clients, the player token is the
+// secret of the web client's player_id cookie, sent as
+// "Authorization: Bearer <token>" or taken from the cookie. Rooms only ever
+// show the public player id the token maps to.
+
+// largest request body the API accepts
+const apiMaxBody = 64 << 10
+
+// handlers of the operations in api.Operations that anyone can call,
+// the actions of players are in apiActions
+var apiHandlers = map[string]http.HandlerFunc{
+	"createRoom": APICreateRoomHandler,
+	"getRoom":    APIRoomHandler,
+	"joinRoom":   APIJoinHandler,
+	"getChat":    APIChatHandler,
+}
+
+// apiRoutes registers the operations, every operation needs a handler or an
+// action and every handler and action an operation so that the OpenAPI
+// document stays complete
+func apiRoutes(r chi.Router) {
+	if len(api.Operations) != len(apiHandlers)+len(apiActions) {
+		log.Fatalf("%d api operations but %d handlers", len(api.Operations), len(apiHandlers)+len(apiActions))
+	}
+	for _, op := range api.Operations {
+		if action, ok := apiActions[op.ID]; ok {
+			r.Method(op.Method, op.Path, apiActionHandler(action, op.Status))
+			continue
+		}
+		handler, ok := apiHandlers[op.ID]
+		if !ok {
+			log.Fatalf("no handler for api operation %s", op.ID)
+		}
+		r.Method(op.Method, op.Path, handler)
+	}
+	r.Handle("/rooms/{roomID}/ws", apiWebsocket)
+}
+
+// apiActionHandler runs the action for the player of the token
+func apiActionHandler(action apiAction, status int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		room, playerID, ok := apiPlayerRoom(w, r)
+		if !ok {
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, apiMaxBody))
+		if err != nil {
+			apiError(w, fmt.Sprintf("bad request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		reply, err := action(room, room.Seat(playerID), body)
+		if err != nil {
+			apiError(w, err.Error(), errorStatus(err))
+			return
+		}
+		writeJSON(w, status, reply)
+	}
+}
+
+// OpenAPIHandler serves the OpenAPI document of the JSON API
+func OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	doc, err := api.OpenAPI(api.Operations)
+	if err != nil {
+		apiError(w, "could not generate the api document", http.StatusInternalServerError)
+		log.Println("error generating openapi document:", err)
+		return
+	}
+	writeJSON(w, http.StatusOK, doc)
+}
+
+func APICreateRoomHandler(w http.ResponseWriter, r *http.Request) {
+	var req api.CreateRoomRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	room := game.NewRoom(req.Mode, strconv.Itoa(req.Dice), req.Rules)
+	if err := room.Configure(req.Mode, strconv.Itoa(req.Dice), req.Rules); err != nil {
+		apiError(w, fmt.Sprintf("bad settings: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.TurnOrder != "" {
+		if err := room.SetTurnOrder(req.TurnOrder); err != nil {
+			apiError(w, fmt.Sprintf("bad turn order: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := room.SetPassphrase(req.Passphrase); err != nil {
+		apiError(w, fmt.Sprintf("bad passphrase: %v", err), http.StatusBadRequest)
+		return
+	}
+	token := apiToken(r)
+	if token == "" {
+		token = game.NewToken()
+	}
+	room.SetHost(token)
+
+	if err := addRoom(room); err != nil {
+		apiError(w, "could not create room", http.StatusInternalServerError)
+		log.Println("error adding room:", err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, api.CreateRoomResponse{RoomID: room.ID, PlayerToken: token, PlayerID: room.HostID})
+}
+
+func APIRoomHandler(w http.ResponseWriter, r *http.Request) {
+	room, ok := findRoom(chi.URLParam(r, "roomID"))
+	if !ok {
+		apiError(w, "room does not exist", http.StatusNotFound)
+		return
+	}
+
+	// like the web client, anyone can watch a public room
+	if room.IsPrivate() && room.GetPlayerByID(room.PlayerFor(apiToken(r))) == nil {
+		apiError(w, "private room", http.StatusForbidden)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, room.APIRoom())
+}
+
+func APIJoinHandler(w http.ResponseWriter, r *http.Request) {
+	room, ok := findRoom(chi.URLParam(r, "roomID"))
+	if !ok {
+		apiError(w, "room does not exist", http.StatusNotFound)
+		return
+	}
+
+	var req api.JoinRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+
+	if room.IsPrivate() {
+		ip := clientIP(r)
+		if !passphraseThrottle.Allowed(ip) {
+			apiError(w, "too many wrong passphrases, try again later", http.StatusTooManyRequests)
+			return
+		}
+		if !room.CheckPassphrase(req.Passphrase) {
+			passphraseThrottle.Fail(ip)
+			apiError(w, "wrong passphrase", http.StatusForbidden)
+			log.Printf("wrong passphrase for room %s from %s\n", room.ID, ip)
+			return
+		}
+		passphraseThrottle.Reset(ip)
+	}
+
+	token := apiToken(r)
+	if token == "" {
+		token = game.NewToken()
+	}
+	playerID := room.PlayerFor(token)
+	acc := currentAccount(r)
+	if playerID == "" && acc != nil {
+		playerID = rebindAccount(room, acc, token)
+	}
+	if room.GetPlayerByID(playerID) != nil {
+		// already joined
+		writeJSON(w, http.StatusOK, api.JoinResponse{PlayerToken: token, PlayerID: playerID, Room: room.APIRoom()})
+		return
+	}
+
+	if strings.TrimSpace(req.Username) == "" {
+		apiError(w, "username is required", http.StatusBadRequest)
+		return
+	}
+
+	if !room.InLobby() {
+		apiError(w, "game already started", http.StatusForbidden)
+		return
+	}
+
+	if room.HotSeat && !room.IsHost(room.PlayerFor(token)) {
+		apiError(w, "this room is played on one device", http.StatusForbidden)
+		return
+	}
+
+	if room.IsFull() {
+		apiError(w, "room full", http.StatusForbidden)
+		return
+	}
+
+	player := room.NewPlayer(token, req.Username)
+	if acc != nil {
+		player.AccountID = acc.ID
+	}
+	if err := room.AddPlayer(player); err != nil {
+		apiError(w, fmt.Sprintf("could not add player: %v", err), http.StatusForbidden)
+		log.Println("error adding player to room:", err)
+		return
+	}
+
+	if err := saveRoom(room); err != nil {
+		apiError(w, err.Error(), errorStatus(err))
+		return
+	}
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.PlayerJoined, Data: room.LobbyPayload()})
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.ScoreUpdated, Data: room.ScoresPayload()})
+	if room.GetState() == game.StatePlaying {
+		room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.GameStarted, Data: room.LobbyPayload()})
+	}
+
+	writeJSON(w, http.StatusCreated, api.JoinResponse{PlayerToken: token, PlayerID: player.ID, Room: room.APIRoom()})
+}
+
+func APIChatHandler(w http.ResponseWriter, r *http.Request) {
+	room, ok := findRoom(chi.URLParam(r, "roomID"))
+	if !ok {
+		apiError(w, "room does not exist", http.StatusNotFound)
+		return
+	}
+
+	if room.IsPrivate() && room.GetPlayerByID(room.PlayerFor(apiToken(r))) == nil {
+		apiError(w, "private room", http.StatusForbidden)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, room.APIChat())
+}
+
+// apiPlayerRoom finds the room of the url and the player of the token,
+// writing the error when either is missing
+func apiPlayerRoom(w http.ResponseWriter, r *http.Request) (*game.Room, string, bool) {
+	room, ok := findRoom(chi.URLParam(r, "roomID"))
+	if !ok {
+		apiError(w, "room does not exist", http.StatusNotFound)
+		return nil, "", false
+	}
+
+	token := apiToken(r)
+	if token == "" {
+		apiError(w, "no player token", http.StatusUnauthorized)
+		return nil, "", false
+	}
+	playerID := room.PlayerFor(token)
+	if room.GetPlayerByID(playerID) == nil {
+		apiError(w, game.ErrNotInRoom.Error(), http.StatusForbidden)
+		return nil, "", false
+	}
+	return room, playerID, true
+}
+
+// apiToken returns the player token of the request, empty when there is none
+func apiToken(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	if playerCookie, err := r.Cookie("player_id"); err == nil {
+		return playerCookie.Value
+	}
+	return ""
+}
+
+// readJSON decodes the request body, an empty body leaves v as it is
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxBody)).Decode(v)
+	if err != nil && !errors.Is(err, io.EOF) {
+		apiError(w, fmt.Sprintf("bad request body: %v", err), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("error encoding api response:", err)
+	}
+}
+
+func apiError(w http.ResponseWriter, msg string, status int) {
+	writeJSON(w, status, api.Error{Error: msg})
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"yamb/api"
+)
+
+// openAPIDoc fetches the document the server serves, decoded the way any
+// client of the API would read it
+func openAPIDoc(t *testing.T, srv *httptest.Server) map[string]any {
+	t.Helper()
+	resp, err := http.Get(srv.URL + "/api/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var doc map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+// apiCall is one request of TestOpenAPI, path relative to api.BasePath
+type apiCall struct {
+	op     string
+	path   string
+	token  string
+	body   any
+	status int
+}
+
+// every operation is called through the router, the status has to be one the
+// document lists for the operation and the body has to match its schema
+func TestOpenAPI(t *testing.T) {
+	srv := newTestServer(t)
+	doc := openAPIDoc(t, srv)
+
+	call := func(c apiCall) map[string]any {
+		t.Helper()
+		i := slices.IndexFunc(api.Operations, func(op api.Operation) bool { return op.ID == c.op })
+		if i < 0 {
+			t.Fatalf("no operation %s", c.op)
+		}
+		op := api.Operations[i]
+
+		var body bytes.Buffer
+		if c.body != nil {
+			if err := json.NewEncoder(&body).Encode(c.body); err != nil {
+				t.Fatal(err)
+			}
+		}
+		req, err := http.NewRequest(op.Method, srv.URL+api.BasePath+c.path, &body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var reply any
+		if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+			t.Fatalf("%s: %v", c.op, err)
+		}
+		if resp.StatusCode != c.status {
+			t.Fatalf("%s: status %d, want %d: %v", c.op, resp.StatusCode, c.status, reply)
+		}
+
+		path, _ := doc["paths"].(map[string]any)[api.BasePath+op.Path].(map[string]any)
+		operation, _ := path[strings.ToLower(op.Method)].(map[string]any)
+		if operation["operationId"] != op.ID {
+			t.Fatalf("%s: not in the document", c.op)
+		}
+		response, ok := operation["responses"].(map[string]any)[strconv.Itoa(resp.StatusCode)].(map[string]any)
+		if !ok {
+			t.Fatalf("%s: status %d is not documented", c.op, resp.StatusCode)
+		}
+		schema := response["content"].(map[string]any)["application/json"].(map[string]any)["schema"]
+		for _, problem := range matchSchema(doc, schema, reply, c.op) {
+			t.Error(problem)
+		}
+		object, _ := reply.(map[string]any)
+		return object
+	}
+
+	created := call(apiCall{op: "createRoom", path: "/rooms", status: http.StatusCreated,
+		body: api.CreateRoomRequest{Mode: "1v1", Dice: 6, Rules: "classic", TurnOrder: "join"}})
+	room := "/rooms/" + created["room_id"].(string)
+	host := created["player_token"].(string)
+	join := api.JoinRequest{Username: "host"}
+	call(apiCall{op: "joinRoom", path: room + "/join", token: host, body: join, status: http.StatusCreated})
+	call(apiCall{op: "joinRoom", path: room + "/join", token: host, body: join, status: http.StatusOK})
+	guest := call(apiCall{op: "joinRoom", path: room + "/join", body: api.JoinRequest{Username: "guest"},
+		status: http.StatusCreated})["player_token"].(string)
+
+	calls := []apiCall{
+		{op: "getRoom", path: room, status: http.StatusOK},
+		{op: "setReady", path: room + "/ready", token: host, body: api.ReadyRequest{Ready: true}, status: http.StatusOK},
+		{op: "setReady", path: room + "/ready", token: guest, body: api.ReadyRequest{Ready: true}, status: http.StatusOK},
+		{op: "startGame", path: room + "/start", token: host, status: http.StatusOK},
+		{op: "rollDice", path: room + "/roll", token: host, status: http.StatusOK},
+	}
+	// the maximum needs all the dice
+	for die := range 6 {
+		calls = append(calls, apiCall{op: "holdDie", path: room + "/hold", token: host,
+			body: api.HoldRequest{Die: die, Held: die < 5}, status: http.StatusOK})
+	}
+	calls = append(calls, []apiCall{
+		{op: "selectCell", path: room + "/select", token: host, body: api.SelectRequest{Row: "max", Col: "announced"}, status: http.StatusOK},
+		{op: "announce", path: room + "/announce", token: host, status: http.StatusOK},
+		{op: "writeScore", path: room + "/write", token: host, status: http.StatusOK},
+		{op: "sendChat", path: room + "/chat", token: guest, body: api.ChatRequest{Message: "hi"}, status: http.StatusCreated},
+		{op: "getChat", path: room + "/chat", status: http.StatusOK},
+		{op: "getRoom", path: room, status: http.StatusOK},
+	}...)
+	called := map[string]bool{"createRoom": true, "joinRoom": true}
+	for _, c := range calls {
+		call(c)
+		called[c.op] = true
+	}
+	for _, op := range api.Operations {
+		if !called[op.ID] {
+			t.Errorf("operation %s is not tested", op.ID)
+		}
+	}
+}
+
+// matchSchema returns how the value differs from the schema of the document,
+// at names where in the reply
+func matchSchema(doc map[string]any, schema, value any, at string) []string {
+	s, _ := schema.(map[string]any)
+	if ref, ok := s["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/components/schemas/")
+		resolved, ok := doc["components"].(map[string]any)["schemas"].(map[string]any)[name]
+		if !ok {
+			return []string{fmt.Sprintf("%s: unknown schema %s", at, ref)}
+		}
+		return matchSchema(doc, resolved, value, at)
+	}
+	if value == nil {
+		if s["nullable"] == true {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: null but not nullable", at)}
+	}
+
+	var problems []string
+	switch s["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: %T, want an object", at, value)}
+		}
+		properties, _ := s["properties"].(map[string]any)
+		required, _ := s["required"].([]any)
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: required %s missing", at, name))
+			}
+		}
+		for name, v := range object {
+			property, ok := properties[name]
+			if !ok {
+				property, ok = s["additionalProperties"]
+			}
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s: %s is not documented", at, name))
+				continue
+			}
+			problems = append(problems, matchSchema(doc, property, v, at+"."+name)...)
+		}
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: %T, want an array", at, value)}
+		}
+		for i, v := range array {
+			problems = append(problems, matchSchema(doc, s["items"], v, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return []string{fmt.Sprintf("%s: %T, want a string", at, value)}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s: %T, want a boolean", at, value)}
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != float64(int64(n)) {
+			return []string{fmt.Sprintf("%s: %v, want an integer", at, value)}
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return []string{fmt.Sprintf("%s: %T, want a number", at, value)}
+		}
+	default:
+		return []string{fmt.Sprintf("%s: schema without a type", at)}
+	}
+	return problems
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+	"yamb/api"
+	"yamb/broadcaster"
+	"yamb/game"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/net/websocket"
+)
+
+// clients without an Authorization header have this long to send their token
+const websocketAuthTimeout = 10 * time.Second
+
+// player websocket of the JSON API, the protocol is described in api/ws.go
+var apiWebsocket = websocket.Server{
+	Handshake: checkWebsocketOrigin,
+	Handler:   APIWebsocketHandler,
+}
+
+// checkWebsocketOrigin lets clients without an origin (scripts, apps) in but
+// keeps other sites from using the cookie of a browser
+func checkWebsocketOrigin(config *websocket.Config, r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host != r.Host {
+		return fmt.Errorf("websocket from another origin: %s", origin)
+	}
+	return nil
+}
+
+func APIWebsocketHandler(ws *websocket.Conn) {
+	defer ws.Close()
+	r := ws.Request()
+
+	var mu sync.Mutex
+	writeTimeout := broadcaster.DefaultConfig.WriteTimeout
+	send := func(msg api.ServerMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+		return websocket.JSON.Send(ws, msg)
+	}
+	fail := func(id string, status int, msg string) error {
+		return send(api.ServerMessage{Type: api.MessageError, ID: id, Status: status, Error: msg})
+	}
+
+	room, ok := findRoom(chi.URLParam(r, "roomID"))
+	if !ok {
+		fail("", http.StatusNotFound, "room does not exist")
+		return
+	}
+	writeTimeout = room.Broadcaster.Config.WriteTimeout
+
+	// browsers cannot set headers on websockets, they send the token first
+	token := apiToken(r)
+	authID, sentAuth := "", token == ""
+	if sentAuth {
+		var err error
+		if authID, token, err = receiveAuth(ws); err != nil {
+			fail(authID, http.StatusUnauthorized, err.Error())
+			return
+		}
+	}
+	playerID := room.PlayerFor(token)
+	if room.GetPlayerByID(playerID) == nil {
+		fail(authID, http.StatusForbidden, game.ErrNotInRoom.Error())
+		return
+	}
+	if sentAuth {
+		if err := send(api.ServerMessage{Type: api.MessageAck, ID: authID}); err != nil {
+			return
+		}
+	}
+
+	lastEventID := r.URL.Query().Get("last_event_id")
+	ch, missed, err := room.Broadcaster.Subscribe(playerID, lastEventID, broadcaster.AllTopics...)
+	defer room.Broadcaster.Unsubscribe(ch)
+
+	event := func(ev broadcaster.Event) error {
+		return send(api.ServerMessage{
+			Type:    api.MessageEvent,
+			Event:   string(ev.Name),
+			EventID: room.Broadcaster.EventID(ev),
+			Data:    ev.Data,
+		})
+	}
+
+	// new clients and clients that missed too much start from the whole state,
+	// resuming clients only get what they missed
+	if lastEventID == "" || errors.Is(err, broadcaster.ErrResync) {
+		if err := send(api.ServerMessage{Type: api.MessageState, Data: room.APIRoom()}); err != nil {
+			return
+		}
+	}
+	for _, ev := range missed {
+		if err := event(ev); err != nil {
+			return
+		}
+	}
+
+	go func() {
+		// closing the connection ends the receive loop below
+		defer ws.Close()
+		for ev := range ch {
+			if err := event(ev); err != nil {
+				return
+			}
+			// events were dropped, the client resumes with last_event_id
+			if len(ch) == 0 && room.Broadcaster.Behind(ch) {
+				return
+			}
+		}
+	}()
+
+	for {
+		var data []byte
+		if err := websocket.Message.Receive(ws, &data); err != nil {
+			return
+		}
+		var msg api.ClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			fail("", http.StatusBadRequest, fmt.Sprintf("bad message: %v", err))
+			continue
+		}
+
+		// the seat may have moved to another device (a resume link, an
+		// account signing in elsewhere) since connecting, the old token
+		// does not act for it any more
+		if room.PlayerFor(token) != playerID {
+			fail(msg.ID, http.StatusForbidden, game.ErrNotInRoom.Error())
+			return
+		}
+
+		reply, err := websocketRequest(room, playerID, msg)
+		if err != nil {
+			err = fail(msg.ID, errorStatus(err), err.Error())
+		} else {
+			err = send(api.ServerMessage{Type: api.MessageAck, ID: msg.ID, Data: reply})
+		}
+		if err != nil {
+			log.Println("error answering websocket request:", err)
+			return
+		}
+	}
+}
+
+// receiveAuth reads the auth request a client without an Authorization
+// header must start with, returning its id and the token
+func receiveAuth(ws *websocket.Conn) (string, string, error) {
+	ws.SetReadDeadline(time.Now().Add(websocketAuthTimeout))
+	defer ws.SetReadDeadline(time.Time{})
+
+	var msg api.ClientMessage
+	if err := websocket.JSON.Receive(ws, &msg); err != nil {
+		return "", "", fmt.Errorf("no player token: %v", err)
+	}
+	if msg.Type != api.MessageAuth {
+		return msg.ID, "", errors.New("no player token, send an auth request first")
+	}
+	var req api.AuthRequest
+	if err := json.Unmarshal(msg.Data, &req); err != nil || req.Token == "" {
+		return msg.ID, "", errors.New("auth request without a token")
+	}
+	return msg.ID, req.Token, nil
+}
+
+// websocketRequest runs the operation of the message
+func websocketRequest(room *game.Room, playerID string, msg api.ClientMessage) (any, error) {
+	switch msg.Type {
+	case "getRoom":
+		return room.APIRoom(), nil
+	case "getChat":
+		return room.APIChat(), nil
+	}
+	action, ok := apiActions[msg.Type]
+	if !ok {
+		return nil, failed(http.StatusBadRequest, "unknown message type %q", msg.Type)
+	}
+	// the player may have been kicked since connecting
+	if room.GetPlayerByID(playerID) == nil {
+		return nil, failed(http.StatusForbidden, "%v", game.ErrNotInRoom)
+	}
+	return action(room, room.Seat(playerID), msg.Data)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"yamb/api"
+	"yamb/game"
+
+	"golang.org/x/net/websocket"
+)
+
+// joinAPI creates a room over the JSON API and joins it, returning the room
+// id and the player token
+func joinAPI(t *testing.T, srv *httptest.Server) (string, string) {
+	t.Helper()
+	var created api.CreateRoomResponse
+	postJSON(t, srv, "/rooms", "", api.CreateRoomRequest{Mode: "1v1", Dice: 6, Rules: "classic"}, &created)
+	var joined api.JoinResponse
+	postJSON(t, srv, "/rooms/"+created.RoomID+"/join", created.PlayerToken, api.JoinRequest{Username: "host"}, &joined)
+	return created.RoomID, created.PlayerToken
+}
+
+func postJSON(t *testing.T, srv *httptest.Server, path, token string, body, reply any) {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodPost, srv.URL+api.BasePath+path, bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		t.Fatalf("POST %s: %s", path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(reply); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func dialWebsocket(t *testing.T, srv *httptest.Server, path string) *websocket.Conn {
+	t.Helper()
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+api.BasePath+path, "", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+func receive(t *testing.T, ws *websocket.Conn) api.ServerMessage {
+	t.Helper()
+	var msg api.ServerMessage
+	if err := websocket.JSON.Receive(ws, &msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestWebsocketAuth(t *testing.T) {
+	srv := newTestServer(t)
+	roomID, token := joinAPI(t, srv)
+
+	t.Run("first message", func(t *testing.T) {
+		ws := dialWebsocket(t, srv, "/rooms/"+roomID+"/ws")
+		auth := api.ClientMessage{ID: "0", Type: api.MessageAuth, Data: json.RawMessage(`{"token":"` + token + `"}`)}
+		if err := websocket.JSON.Send(ws, auth); err != nil {
+			t.Fatal(err)
+		}
+		if msg := receive(t, ws); msg.Type != api.MessageAck || msg.ID != "0" {
+			t.Fatalf("answer to auth = %+v, want an ack", msg)
+		}
+		if msg := receive(t, ws); msg.Type != api.MessageState {
+			t.Fatalf("first message after auth = %+v, want the state", msg)
+		}
+	})
+
+	t.Run("header", func(t *testing.T) {
+		config, err := websocket.NewConfig("ws"+strings.TrimPrefix(srv.URL, "http")+api.BasePath+"/rooms/"+roomID+"/ws", srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		config.Header.Set("Authorization", "Bearer "+token)
+		ws, err := websocket.DialConfig(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ws.Close()
+		if msg := receive(t, ws); msg.Type != api.MessageState {
+			t.Fatalf("first message = %+v, want the state", msg)
+		}
+	})
+
+	// tokens in the url end up in access logs
+	t.Run("url", func(t *testing.T) {
+		ws := dialWebsocket(t, srv, "/rooms/"+roomID+"/ws?token="+token)
+		if err := websocket.JSON.Send(ws, api.ClientMessage{ID: "1", Type: "getRoom"}); err != nil {
+			t.Fatal(err)
+		}
+		if msg := receive(t, ws); msg.Type != api.MessageError || msg.Status != http.StatusUnauthorized {
+			t.Fatalf("answer without auth = %+v, want an unauthorized error", msg)
+		}
+	})
+
+	t.Run("wrong token", func(t *testing.T) {
+		ws := dialWebsocket(t, srv, "/rooms/"+roomID+"/ws")
+		auth := api.ClientMessage{ID: "0", Type: api.MessageAuth, Data: json.RawMessage(`{"token":"guess"}`)}
+		if err := websocket.JSON.Send(ws, auth); err != nil {
+			t.Fatal(err)
+		}
+		if msg := receive(t, ws); msg.Type != api.MessageError || msg.ID != "0" || msg.Status != http.StatusForbidden {
+			t.Fatalf("answer to a wrong token = %+v, want a forbidden error", msg)
+		}
+	})
+}
+
+// a socket acts for the seat only as long as its token holds the seat
+func TestWebsocketRebind(t *testing.T) {
+	srv := newTestServer(t)
+	roomID, token := joinAPI(t, srv)
+	ws := dialWebsocket(t, srv, "/rooms/"+roomID+"/ws")
+	auth := api.ClientMessage{ID: "0", Type: api.MessageAuth, Data: json.RawMessage(`{"token":"` + token + `"}`)}
+	if err := websocket.JSON.Send(ws, auth); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, ws) // ack
+	receive(t, ws) // state
+
+	room, _ := findRoom(roomID)
+	if err := room.Rebind(room.PlayerFor(token), game.NewToken()); err != nil {
+		t.Fatal(err)
+	}
+	ready := api.ClientMessage{ID: "1", Type: "setReady", Data: json.RawMessage(`{"ready":true}`)}
+	if err := websocket.JSON.Send(ws, ready); err != nil {
+		t.Fatal(err)
+	}
+	msg := receive(t, ws)
+	for msg.Type == api.MessageEvent {
+		msg = receive(t, ws)
+	}
+	if msg.Type != api.MessageError || msg.ID != "1" || msg.Status != http.StatusForbidden {
+		t.Fatalf("answer on the old token = %+v, want a forbidden error", msg)
+	}
+	if room.GetPlayerByID(room.HostID).Ready {
+		t.Error("the old token made the player ready")
+	}
+	for {
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			break
+		}
+		if msg.Type != api.MessageEvent {
+			t.Fatalf("socket of the old token answers: %+v", msg)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package broadcaster
+
+import (
+	"sync"
+	"yamb/resp"
+)
+
+// Backend carries the published events to every server instance, the
+// broadcaster of each instance delivers them to its own subscribers
+type Backend interface {
+	Publish(channel string, data []byte) error
+	// Subscribe calls handler with every message published on the channel,
+	// until the returned function is called
+	Subscribe(channel string, handler func(data []byte)) (func(), error)
+}
+
+// MemoryBackend delivers the events inside the process, enough for a single instance
+type MemoryBackend struct {
+	mu       sync.Mutex
+	handlers map[string]func(data []byte)
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{handlers: make(map[string]func(data []byte))}
+}
+
+func (m *MemoryBackend) Publish(channel string, data []byte) error {
+	m.mu.Lock()
+	handler := m.handlers[channel]
+	m.mu.Unlock()
+	if handler != nil {
+		handler(data)
+	}
+	return nil
+}
+
+func (m *MemoryBackend) Subscribe(channel string, handler func(data []byte)) (func(), error) {
+	m.mu.Lock()
+	m.handlers[channel] = handler
+	m.mu.Unlock()
+	return func() {
+		m.mu.Lock()
+		delete(m.handlers, channel)
+		m.mu.Unlock()
+	}, nil
+}
+
+// RedisBackend carries the events over Redis pub/sub (or any server speaking
+// its protocol), so that several instances can serve the same rooms
+type RedisBackend struct {
+	client *resp.Client
+	pubsub *resp.PubSub
+
+	mu       sync.Mutex
+	handlers map[string]func(data []byte)
+}
+
+func NewRedisBackend(url string) (*RedisBackend, error) {
+	client, err := resp.Dial(url)
+	if err != nil {
+		return nil, err
+	}
+	b := &RedisBackend{client: client, handlers: make(map[string]func(data []byte))}
+	b.pubsub, err = resp.NewPubSub(url, b.receive)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *RedisBackend) receive(channel string, data []byte) {
+	b.mu.Lock()
+	handler := b.handlers[channel]
+	b.mu.Unlock()
+	if handler != nil {
+		handler(data)
+	}
+}
+
+func (b *RedisBackend) Publish(channel string, data []byte) error {
+	_, err := b.client.Do("PUBLISH", channel, string(data))
+	return err
+}
+
+func (b *RedisBackend) Subscribe(channel string, handler func(data []byte)) (func(), error) {
+	b.mu.Lock()
+	b.handlers[channel] = handler
+	b.mu.Unlock()
+	if err := b.pubsub.Subscribe(channel); err != nil {
+		return nil, err
+	}
+	return func() {
+		b.mu.Lock()
+		delete(b.handlers, channel)
+		b.mu.Unlock()
+		b.pubsub.Unsubscribe(channel)
+	}, nil
+}
+
+func (b *RedisBackend) Close() error {
+	b.pubsub.Close()
+	return b.client.Close()
+}
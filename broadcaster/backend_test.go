@@ -0,0 +1,45 @@
+package broadcaster
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+	"yamb/resp/resptest"
+)
+
+// two instances share the events of a room through the redis backend
+func TestRedisBackend(t *testing.T) {
+	srv := resptest.NewServer(t, "")
+	instances := make([]*Broadcaster, 2)
+	for i := range instances {
+		backend, err := NewRedisBackend(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { backend.Close() })
+		instances[i] = NewBroadcaster()
+		if err := instances[i].Connect(backend, "yamb:room:123456:events"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ch, _, err := instances[1].Subscribe("", "", TopicGame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the subscription reaches the server a moment after Connect returns
+	deadline := time.After(5 * time.Second)
+	for {
+		instances[0].Broadcast(Event{Name: DiceAreaUpdated, Data: map[string]int{"rolls_left": 2}})
+		select {
+		case ev := <-ch:
+			if ev.Name != DiceAreaUpdated || string(ev.Data.(json.RawMessage)) != `{"rolls_left":2}` {
+				t.Fatalf("received %s %s", ev.Name, ev.Data)
+			}
+			return
+		case <-deadline:
+			t.Fatal("the event did not reach the other instance")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
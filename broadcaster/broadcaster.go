@@ -1,41 +1,336 @@
 package broadcaster
 
-import "sync"
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// how many recent events are kept for clients that reconnect
+const historySize = 64
+
+// ErrResync means the events a client missed are no longer buffered,
+// it has to load the whole room again
+var ErrResync = errors.New("missed events are no longer available")
+
+// tuning of the event delivery
+type Config struct {
+	BufferSize   int           // events queued per subscriber
+	Heartbeat    time.Duration // interval of the keep-alive comments on the event stream
+	WriteTimeout time.Duration // deadline for writing one event to a client
+	MaxLag       time.Duration // how long a subscriber may stay behind before it is evicted
+}
+
+// used by NewBroadcaster
+var DefaultConfig = Config{
+	BufferSize:   16,
+	Heartbeat:    15 * time.Second,
+	WriteTimeout: 10 * time.Second,
+	MaxLag:       30 * time.Second,
+}
+
+// counters of every broadcaster, served at /debug/vars on DEBUG_ADDR
+var metrics = expvar.NewMap("broadcaster")
+
+// tells the events of this process apart from the ones of other instances
+var instance = strconv.FormatInt(time.Now().UnixNano(), 36)
 
 type Broadcaster struct {
-	subscribers map[chan Event]struct{}
+	Config Config
+	// called before an event published by another server instance is
+	// delivered, e.g. to load the state that instance saved
+	OnRemote func(Event)
+
+	backend     Backend
+	channel     string
+	unsubscribe func()
+
+	subscribers map[chan Event]*subscriber
+	online      map[string]int             // player id -> number of subscriptions
+	evicted     map[chan Event]*subscriber // closed for falling behind, not yet unsubscribed
 	mu          sync.Mutex
+
+	// ids are "<epoch>-<n>", the epoch tells ids of this broadcaster apart
+	// from ids handed out before the server restarted
+	epoch   string
+	lastID  uint64
+	history []sent // ring buffer of the last historySize events
 }
 
+type subscriber struct {
+	playerID string
+	topics   []Topic
+	// when the channel filled up, zero while the subscriber keeps up. A
+	// subscriber that fell behind gets no events until it reconnects so that
+	// its last event id still tells which events it missed.
+	behind time.Time
+}
+
+// event as it was published, to is empty for events sent to everyone
+type sent struct {
+	event Event
+	to    string
+}
+
+// event as it travels through the backend
+type envelope struct {
+	Origin string          `json:"origin"`
+	To     string          `json:"to,omitempty"`
+	Name   EventName       `json:"name"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+// NewBroadcaster returns a broadcaster delivering inside the process,
+// see Connect for sharing the events with other instances
 func NewBroadcaster() *Broadcaster {
-	return &Broadcaster{
-		subscribers: make(map[chan Event]struct{}),
+	b := &Broadcaster{
+		Config:      DefaultConfig,
+		subscribers: make(map[chan Event]*subscriber),
+		online:      make(map[string]int),
+		evicted:     make(map[chan Event]*subscriber),
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		history:     make([]sent, 0, historySize),
 	}
+	// a memory backend cannot fail
+	b.Connect(NewMemoryBackend(), "events")
+	return b
 }
 
-func (b *Broadcaster) Subscribe() chan Event {
-	ch := make(chan Event, 5)
+// Connect publishes and receives the events through the channel of the backend
+func (b *Broadcaster) Connect(backend Backend, channel string) error {
+	unsubscribe, err := backend.Subscribe(channel, b.receive)
+	if err != nil {
+		return err
+	}
 	b.mu.Lock()
-	b.subscribers[ch] = struct{}{}
+	previous := b.unsubscribe
+	b.backend, b.channel, b.unsubscribe = backend, channel, unsubscribe
 	b.mu.Unlock()
-	return ch
+	if previous != nil {
+		previous()
+	}
+	return nil
+}
+
+// Close stops receiving events from the backend
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	unsubscribe := b.unsubscribe
+	b.unsubscribe = nil
+	b.mu.Unlock()
+	if unsubscribe != nil {
+		unsubscribe()
+	}
+}
+
+// Subscribe registers a channel for the events of the topics, a player can
+// have several (one per open tab), spectators subscribe with an empty id.
+// lastEventID is the id of the last event the client got before reconnecting
+// (empty for new clients), the events it missed since are returned,
+// or ErrResync when they are no longer buffered.
+// The channel is closed when the subscriber stays behind longer than MaxLag.
+func (b *Broadcaster) Subscribe(playerID, lastEventID string, topics ...Topic) (chan Event, []Event, error) {
+	ch := make(chan Event, b.Config.BufferSize)
+	sub := &subscriber{playerID: playerID, topics: topics}
+	b.mu.Lock()
+	b.subscribers[ch] = sub
+	online := b.connected(playerID, 1)
+	var missed []Event
+	var err error
+	if lastEventID != "" {
+		missed, err = b.since(sub, lastEventID)
+	}
+	b.mu.Unlock()
+
+	if online {
+		b.Broadcast(Event{Name: PlayerOnline, Data: PresencePayload{PlayerID: playerID}})
+	}
+	return ch, missed, err
+}
+
+// connected counts the subscriptions of the player and reports whether the
+// player came online or went offline, caller must hold the lock
+func (b *Broadcaster) connected(playerID string, delta int) bool {
+	if playerID == "" {
+		return false
+	}
+	b.online[playerID] += delta
+	n := b.online[playerID]
+	if n == 0 {
+		delete(b.online, playerID)
+	}
+	return (delta > 0 && n == 1) || (delta < 0 && n == 0)
+}
+
+// Online reports whether the player has a subscription on this instance
+func (b *Broadcaster) Online(playerID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.online[playerID] > 0
+}
+
+// Behind reports whether events were dropped for the channel, the client
+// should reconnect to get them once it handled the queued ones
+func (b *Broadcaster) Behind(ch chan Event) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sub, ok := b.subscribers[ch]
+	return ok && !sub.behind.IsZero()
+}
+
+// caller must hold the lock
+func (b *Broadcaster) since(sub *subscriber, lastEventID string) ([]Event, error) {
+	epoch, n, ok := strings.Cut(lastEventID, "-")
+	if !ok || epoch != b.epoch {
+		return nil, ErrResync
+	}
+	last, err := strconv.ParseUint(n, 10, 64)
+	if err != nil || last > b.lastID {
+		return nil, ErrResync
+	}
+	if last == b.lastID {
+		return nil, nil
+	}
+	if len(b.history) == 0 || b.history[0].event.ID > last+1 {
+		return nil, ErrResync
+	}
+	missed := []Event{}
+	for _, s := range b.history {
+		if s.event.ID > last && sub.wants(s.to, s.event) {
+			missed = append(missed, s.event)
+		}
+	}
+	return missed, nil
 }
 
 func (b *Broadcaster) Unsubscribe(ch chan Event) {
 	b.mu.Lock()
-	delete(b.subscribers, ch)
-	close(ch)
+	sub, ok := b.subscribers[ch]
+	if ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+	// evicted subscribers were still connected
+	if sub == nil {
+		sub = b.evicted[ch]
+		delete(b.evicted, ch)
+	}
+	offline := sub != nil && b.connected(sub.playerID, -1)
 	b.mu.Unlock()
+
+	if offline {
+		b.Broadcast(Event{Name: PlayerOffline, Data: PresencePayload{PlayerID: sub.playerID}})
+	}
+}
+
+// wants reports whether the event published to the player (or everyone when
+// empty) is for the subscriber
+func (sub *subscriber) wants(to string, event Event) bool {
+	return (to == "" || to == sub.playerID) && slices.Contains(sub.topics, event.Name.Topic())
 }
 
+// Broadcast sends the event to everyone in the room
 func (b *Broadcaster) Broadcast(event Event) {
-	b.mu.Lock()
-	for ch := range b.subscribers {
-		select {
-		case ch <- event:
-		default:
-			// Drop the event if the channel is full
+	b.publish("", event)
+}
+
+// Send sends the event only to the channels of the player
+func (b *Broadcaster) Send(playerID string, event Event) {
+	if playerID == "" {
+		return
+	}
+	b.publish(playerID, event)
+}
+
+// EventID returns the id of the event for the SSE id field
+func (b *Broadcaster) EventID(event Event) string {
+	return fmt.Sprintf("%s-%d", b.epoch, event.ID)
+}
+
+// publish hands the event to the backend, which passes it to receive on
+// every instance
+func (b *Broadcaster) publish(to string, event Event) {
+	env := envelope{Origin: instance, To: to, Name: event.Name}
+	if event.Data != nil {
+		data, err := json.Marshal(event.Data)
+		if err != nil {
+			log.Println("error encoding event payload:", err)
+			return
 		}
+		env.Data = data
+	}
+	data, err := json.Marshal(env)
+	if err != nil {
+		log.Println("error encoding event:", err)
+		return
 	}
+	b.mu.Lock()
+	backend, channel := b.backend, b.channel
 	b.mu.Unlock()
+	if err := backend.Publish(channel, data); err != nil {
+		log.Println("error publishing event:", err)
+	}
+}
+
+func (b *Broadcaster) receive(data []byte) {
+	env := envelope{}
+	if err := json.Unmarshal(data, &env); err != nil {
+		log.Println("error decoding event:", err)
+		return
+	}
+	event := Event{Name: env.Name}
+	if len(env.Data) > 0 {
+		event.Data = env.Data
+	}
+	if env.Origin != instance && b.OnRemote != nil {
+		b.OnRemote(event)
+	}
+	b.deliver(env.To, event)
+	if event.Name == RoomCancelled {
+		// nothing follows the end of a room
+		b.Close()
+	}
+}
+
+// deliver numbers the event, keeps it in the history and queues it for the
+// subscribers, to is empty for everyone
+func (b *Broadcaster) deliver(to string, event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	event.ID = b.lastID
+	if len(b.history) == historySize {
+		b.history = append(b.history[:0], b.history[1:]...)
+	}
+	b.history = append(b.history, sent{event: event, to: to})
+
+	metrics.Add("published", 1)
+
+	for ch, sub := range b.subscribers {
+		if !sub.wants(to, event) {
+			continue
+		}
+		if sub.behind.IsZero() {
+			select {
+			case ch <- event:
+				continue
+			default:
+				sub.behind = time.Now()
+			}
+		}
+		metrics.Add("dropped", 1)
+		if time.Since(sub.behind) > b.Config.MaxLag {
+			delete(b.subscribers, ch)
+			b.evicted[ch] = sub
+			close(ch)
+			metrics.Add("evicted", 1)
+		}
+	}
 }
@@ -1,17 +1,82 @@
 package broadcaster
 
+import "encoding/json"
+
+// every event carries a payload (see payload.go), serialized as JSON into the
+// SSE data field, the comment next to the name tells which one
+
 type EventName string
 
 const (
-	PlayerJoined    EventName = "playerJoined"
-	ScoreUpdated    EventName = "scoreUpdated"
-	DiceAreaUpdated EventName = "diceAreaUpdated"
-	CellSelected    EventName = "cellSelected"
-	TurnEnded       EventName = "turnEnded"
-	ScoreAnnounced  EventName = "scoreAnnounced"
-	GameEnded       EventName = "gameEnded"
+	PlayerJoined    EventName = "playerJoined"    // LobbyPayload
+	ScoreUpdated    EventName = "scoreUpdated"    // ScoresPayload
+	DiceAreaUpdated EventName = "diceAreaUpdated" // DicePayload
+	CellSelected    EventName = "cellSelected"    // CellPayload
+	TurnEnded       EventName = "turnEnded"       // TurnPayload
+	ScoreAnnounced  EventName = "scoreAnnounced"  // CellPayload
+	GameEnded       EventName = "gameEnded"       // ScoresPayload, best first
+
+	// lobby
+	PlayerReady EventName = "playerReady" // LobbyPayload
+	GameStarted EventName = "gameStarted" // LobbyPayload
+
+	// host moderation
+	PlayerKicked     EventName = "playerKicked"     // LobbyPayload
+	TurnOrderChanged EventName = "turnOrderChanged" // LobbyPayload
+	SettingsChanged  EventName = "settingsChanged"  // LobbyPayload
+	HostTransferred  EventName = "hostTransferred"  // LobbyPayload
+	RoomCancelled    EventName = "roomCancelled"    // no payload
+
+	// pause / adjourn
+	PauseVoted    EventName = "pauseVoted"    // VotePayload
+	GamePaused    EventName = "gamePaused"    // VotePayload
+	GameResumed   EventName = "gameResumed"   // VotePayload
+	GameAdjourned EventName = "gameAdjourned" // VotePayload
+
+	// sent to a single player with Broadcaster.Send
+	YourTurn EventName = "yourTurn" // no payload
+	Kicked   EventName = "kicked"   // no payload
+
+	// chat
+	ChatMessage EventName = "chatMessage" // ChatPayload
+
+	// presence, a player is online while they have a connection to the room
+	PlayerOnline  EventName = "playerOnline"  // PresencePayload
+	PlayerOffline EventName = "playerOffline" // PresencePayload
+
+	// the client missed events that are no longer buffered and has to load the room again
+	Resync EventName = "resync" // no payload
+
+	// HTML rendered by the events stream for the player it belongs to,
+	// sent after the events that change the dice area
+	DiceAreaHTML EventName = "diceAreaHTML"
 )
 
 type Event struct {
+	ID   uint64 // set when the event is published, increasing per room
 	Name EventName
+	Data any // one of the payload types, nil for events without one
+}
+
+// Decode unmarshals the payload into v, which should be a pointer to the
+// payload type of the event
+func (e Event) Decode(v any) error {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Payload returns the data of the event as sent in the SSE data field,
+// "_" for events without a payload since SSE messages need some data
+func (e Event) Payload() (string, error) {
+	if e.Data == nil {
+		return "_", nil
+	}
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
 }
@@ -0,0 +1,73 @@
+package broadcaster
+
+import "time"
+
+// payloads of the events, kept free of game types so that clients can decode
+// them without the game package
+
+type PlayerPayload struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Ready    bool   `json:"ready"`
+	Total    int    `json:"total"`
+}
+
+// room settings and players, in turn order once the game started
+type LobbyPayload struct {
+	State     string          `json:"state"`
+	HostID    string          `json:"host_id"`
+	Mode      string          `json:"mode"`
+	Dice      int             `json:"dice"`
+	Rules     string          `json:"rules"`
+	TurnOrder string          `json:"turn_order"`
+	Players   []PlayerPayload `json:"players"`
+}
+
+// dice of the player on turn
+type DicePayload struct {
+	PlayerID  string `json:"player_id"`
+	Values    []int  `json:"values"`
+	Held      []bool `json:"held"`
+	RollsLeft int    `json:"rolls_left"`
+}
+
+// cell selected (empty row and col when unselected) or announced by a player
+type CellPayload struct {
+	PlayerID  string `json:"player_id"`
+	Row       string `json:"row"`
+	Col       string `json:"col"`
+	Announced bool   `json:"announced"`
+}
+
+// score written at the end of a turn
+type TurnPayload struct {
+	PlayerID     string `json:"player_id"`
+	Row          string `json:"row"`
+	Col          string `json:"col"`
+	Score        int    `json:"score"`
+	Total        int    `json:"total"`
+	NextPlayerID string `json:"next_player_id,omitempty"` // empty when the game ended
+}
+
+type ScoresPayload struct {
+	CurrentPlayerID string          `json:"current_player_id"`
+	Players         []PlayerPayload `json:"players"`
+}
+
+// pending pause/resume vote and the state of the game
+type VotePayload struct {
+	State   string   `json:"state"`
+	VoteFor string   `json:"vote_for,omitempty"`
+	Votes   []string `json:"votes,omitempty"` // ids of the players who agreed
+}
+
+type ChatPayload struct {
+	PlayerID string    `json:"player_id"`
+	Username string    `json:"username"`
+	Message  string    `json:"message"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+type PresencePayload struct {
+	PlayerID string `json:"player_id"`
+}
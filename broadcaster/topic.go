@@ -0,0 +1,26 @@
+package broadcaster
+
+// every event belongs to a topic, subscribers pick the topics they want
+type Topic string
+
+const (
+	TopicGame     Topic = "game"     // dice, scores and turns
+	TopicLobby    Topic = "lobby"    // players and settings before the game, the room itself
+	TopicChat     Topic = "chat"     // chat messages
+	TopicPresence Topic = "presence" // players connecting and leaving
+)
+
+// every topic, for subscribers that want all events
+var AllTopics = []Topic{TopicGame, TopicLobby, TopicChat, TopicPresence}
+
+func (n EventName) Topic() Topic {
+	switch n {
+	case PlayerJoined, PlayerReady, PlayerKicked, TurnOrderChanged, SettingsChanged, HostTransferred, RoomCancelled, Kicked:
+		return TopicLobby
+	case ChatMessage:
+		return TopicChat
+	case PlayerOnline, PlayerOffline:
+		return TopicPresence
+	}
+	return TopicGame
+}
@@ -0,0 +1,29 @@
+// yamb-openapi writes the OpenAPI document of the JSON API, the same one the
+// server serves at /api/openapi.json, e.g. to generate clients without a
+// running server.
+//
+//	yamb-openapi > openapi.json
+//
+// Exits with 1 if the document cannot be generated.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"yamb/api"
+)
+
+func main() {
+	doc, err := api.OpenAPI(api.Operations)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
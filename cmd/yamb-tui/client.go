@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"yamb/api"
+
+	"golang.org/x/net/websocket"
+)
+
+// client talks to the JSON API of the server, requests go over the player
+// websocket once it is connected
+type client struct {
+	server   string // base url, e.g. http://localhost:8080
+	token    string // secret, only sent to the server
+	playerID string // how the room knows us
+
+	ws     *websocket.Conn
+	mu     sync.Mutex
+	nextID int
+}
+
+// call does a request of the JSON API over http
+func (c *client) call(method, path string, body, reply any) error {
+	var in bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&in).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, c.server+api.BasePath+path, &in)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var apiErr api.Error
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err != nil || apiErr.Error == "" {
+			return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		}
+		return fmt.Errorf("%s", apiErr.Error)
+	}
+	return json.NewDecoder(resp.Body).Decode(reply)
+}
+
+func (c *client) createRoom(req api.CreateRoomRequest) (string, error) {
+	var reply api.CreateRoomResponse
+	if err := c.call(http.MethodPost, "/rooms", req, &reply); err != nil {
+		return "", err
+	}
+	c.token = reply.PlayerToken
+	return reply.RoomID, nil
+}
+
+func (c *client) join(roomID string, req api.JoinRequest) error {
+	var reply api.JoinResponse
+	if err := c.call(http.MethodPost, "/rooms/"+roomID+"/join", req, &reply); err != nil {
+		return err
+	}
+	c.token = reply.PlayerToken
+	c.playerID = reply.PlayerID
+	return nil
+}
+
+// connect opens the player websocket, the messages of the server go to
+// messages until the connection breaks
+func (c *client) connect(roomID string, messages chan<- message) error {
+	u, err := url.Parse(c.server)
+	if err != nil {
+		return err
+	}
+	origin := u.String()
+	u.Scheme = strings.Replace(u.Scheme, "http", "ws", 1)
+	u.Path = api.BasePath + "/rooms/" + roomID + "/ws"
+
+	config, err := websocket.NewConfig(u.String(), origin)
+	if err != nil {
+		return err
+	}
+	config.Header.Set("Authorization", "Bearer "+c.token)
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		return err
+	}
+	c.ws = ws
+
+	go func() {
+		defer close(messages)
+		for {
+			var msg message
+			if err := websocket.JSON.Receive(ws, &msg); err != nil {
+				return
+			}
+			messages <- msg
+		}
+	}()
+	return nil
+}
+
+// send sends a request over the websocket, returning its id
+func (c *client) send(typ string, data any) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.nextID++
+	id := strconv.Itoa(c.nextID)
+	msg := struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data any    `json:"data,omitempty"`
+	}{id, typ, data}
+	return id, websocket.JSON.Send(c.ws, msg)
+}
+
+func (c *client) close() {
+	if c.ws != nil {
+		c.ws.Close()
+	}
+}
+
+// message is api.ServerMessage with the data left to decode by type
+type message struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id"`
+	Event   string          `json:"event"`
+	EventID string          `json:"event_id"`
+	Status  int             `json:"status"`
+	Error   string          `json:"error"`
+	Data    json.RawMessage `json:"data"`
+}
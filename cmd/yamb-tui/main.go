@@ -0,0 +1,385 @@
+// yamb-tui plays yamb in the terminal over the JSON API of a server.
+//
+//	yamb-tui -name ana                        create a 1v1 room and join it
+//	yamb-tui -name ana -mode 2v2 -dice 5      create a room with other settings
+//	yamb-tui -name ana -room 123456           join a room
+//	yamb-tui -room 123456 -token <token>      come back to a room
+//
+// Keys: arrows (or hjkl) move on the scorecard, enter selects the cell,
+// 1-6 keep/release a die, r rolls, a announces, w writes, c chats,
+// tab shows the other scorecards, R toggles ready, s starts (host), q quits.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"yamb/api"
+	"yamb/broadcaster"
+	"yamb/game"
+)
+
+func main() {
+	server := flag.String("server", "http://localhost:8080", "url of the server")
+	roomID := flag.String("room", "", "room to join, a new room is created when empty")
+	name := flag.String("name", os.Getenv("USER"), "username")
+	token := flag.String("token", "", "player token of an earlier session")
+	passphrase := flag.String("passphrase", "", "passphrase of a private room (or for the new room)")
+	mode := flag.String("mode", game.Mode1v1, "mode of a new room: 1v1, 1v1v1, 2v2 or solo")
+	dice := flag.Int("dice", 6, "dice of a new room: 5 or 6")
+	rules := flag.String("rules", game.RulesClassic, "rules of a new room: classic or extended")
+	flag.Parse()
+
+	c := &client{server: strings.TrimSuffix(*server, "/"), token: *token}
+	if err := run(c, *roomID, *name, *passphrase, api.CreateRoomRequest{
+		Mode:       *mode,
+		Dice:       *dice,
+		Rules:      *rules,
+		Passphrase: *passphrase,
+	}); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(c *client, roomID, name, passphrase string, create api.CreateRoomRequest) error {
+	if roomID == "" {
+		id, err := c.createRoom(create)
+		if err != nil {
+			return fmt.Errorf("could not create room: %w", err)
+		}
+		roomID = id
+	}
+	if err := c.join(roomID, api.JoinRequest{Username: name, Passphrase: passphrase}); err != nil {
+		return fmt.Errorf("could not join room %s: %w", roomID, err)
+	}
+
+	messages := make(chan message, 16)
+	if err := c.connect(roomID, messages); err != nil {
+		return fmt.Errorf("could not connect: %w", err)
+	}
+	defer c.close()
+
+	restore, err := rawMode()
+	if err != nil {
+		return fmt.Errorf("could not set up the terminal: %w", err)
+	}
+	defer restore()
+	keys := make(chan string)
+	go readKeys(keys)
+
+	t := &tui{
+		c:       c,
+		roomID:  roomID,
+		online:  map[string]bool{c.playerID: true},
+		pending: make(map[string]string),
+	}
+	t.request("getChat", nil)
+
+	for !t.quit {
+		t.render(os.Stdout)
+		select {
+		case key, ok := <-keys:
+			if !ok {
+				t.quit = true
+				break
+			}
+			t.key(key)
+		case msg, ok := <-messages:
+			if !ok {
+				fmt.Print(clearScreen)
+				return errors.New("connection to the server closed")
+			}
+			t.message(msg)
+		}
+	}
+	if t.exit != "" {
+		fmt.Print(clearScreen)
+		return errors.New(t.exit)
+	}
+	fmt.Printf("%sroom %s, player token %s\n", clearScreen, roomID, c.token)
+	return nil
+}
+
+// tui is the state of the terminal client
+type tui struct {
+	c      *client
+	roomID string
+	room   api.Room
+	online map[string]bool
+	chat   []api.ChatMessage
+
+	row, col int  // cursor on the scorecard
+	view     int  // scorecard shown, counted in turn order from the own one
+	typing   bool // writing a chat message
+	input    []rune
+	status   string
+
+	pending map[string]string // request id -> type
+	quit    bool
+	exit    string // why the server ended the session
+}
+
+func (t *tui) request(typ string, data any) {
+	id, err := t.c.send(typ, data)
+	if err != nil {
+		t.status = err.Error()
+		return
+	}
+	t.pending[id] = typ
+}
+
+func (t *tui) message(msg message) {
+	switch msg.Type {
+	case api.MessageState:
+		t.decode(msg.Data, &t.room)
+	case api.MessageError:
+		t.status = msg.Error
+		delete(t.pending, msg.ID)
+	case api.MessageAck:
+		typ := t.pending[msg.ID]
+		delete(t.pending, msg.ID)
+		switch typ {
+		case "getChat":
+			t.decode(msg.Data, &t.chat)
+		case "sendChat":
+			// arrives as an event too
+		case "writeScore":
+			var reply api.WriteResponse
+			if t.decode(msg.Data, &reply) {
+				t.room = reply.Room
+				t.status = fmt.Sprintf("wrote %d", reply.Score)
+			}
+		default:
+			t.decode(msg.Data, &t.room)
+		}
+	case api.MessageEvent:
+		t.event(msg)
+	}
+}
+
+func (t *tui) event(msg message) {
+	switch broadcaster.EventName(msg.Event) {
+	case broadcaster.ChatMessage:
+		var chat api.ChatMessage
+		if t.decode(msg.Data, &chat) {
+			t.chat = append(t.chat, chat)
+		}
+	case broadcaster.PlayerOnline, broadcaster.PlayerOffline:
+		var presence broadcaster.PresencePayload
+		if t.decode(msg.Data, &presence) {
+			t.online[presence.PlayerID] = msg.Event == string(broadcaster.PlayerOnline)
+		}
+	case broadcaster.YourTurn:
+		t.status = "your turn"
+		fmt.Print("\a")
+	case broadcaster.Kicked:
+		t.quit, t.exit = true, "the host removed you from the room"
+	case broadcaster.RoomCancelled:
+		t.quit, t.exit = true, "the host cancelled the room"
+	default:
+		// the payloads are parts of the state, getting all of it is simpler
+		t.request("getRoom", nil)
+	}
+}
+
+func (t *tui) decode(data json.RawMessage, v any) bool {
+	if err := json.Unmarshal(data, v); err != nil {
+		t.status = fmt.Sprintf("bad message from the server: %v", err)
+		return false
+	}
+	return true
+}
+
+func (t *tui) key(key string) {
+	if t.typing {
+		t.typeKey(key)
+		return
+	}
+	t.status = ""
+	rows, cols := t.rows(), t.room.Columns
+	switch key {
+	case "q":
+		t.quit = true
+	case keyUp, "k":
+		t.row = max(t.row-1, 0)
+	case keyDown, "j":
+		t.row = max(min(t.row+1, len(rows)-1), 0)
+	case keyLeft, "h":
+		t.col = max(t.col-1, 0)
+	case keyRight, "l":
+		t.col = max(min(t.col+1, len(cols)-1), 0)
+	case keyTab:
+		t.view++
+	case "1", "2", "3", "4", "5", "6":
+		die := int(key[0] - '1')
+		if err := t.canHold(die); err != nil {
+			t.status = err.Error()
+			return
+		}
+		t.request("holdDie", api.HoldRequest{Die: die, Held: !t.room.Roll.Held[die]})
+	case "r", " ":
+		if err := t.canRoll(); err != nil {
+			t.status = err.Error()
+			return
+		}
+		t.request("rollDice", nil)
+	case keyEnter:
+		if len(rows) == 0 || len(cols) == 0 {
+			return
+		}
+		row, col := rows[t.row], cols[t.col]
+		if err := t.canSelect(row, col); err != nil {
+			t.status = err.Error()
+			return
+		}
+		t.request("selectCell", api.SelectRequest{Row: row, Col: col})
+	case "a":
+		if err := t.myTurn(); err != nil {
+			t.status = err.Error()
+			return
+		}
+		t.request("announce", nil)
+	case "w":
+		if _, err := t.preview(); err != nil {
+			t.status = err.Error()
+			return
+		}
+		t.request("writeScore", nil)
+	case "c":
+		t.typing, t.input = true, nil
+	case "R":
+		if me := t.me(); me != nil {
+			t.request("setReady", api.ReadyRequest{Ready: !me.Ready})
+		}
+	case "s":
+		if t.room.HostID != t.c.playerID {
+			t.status = "only the host can start the game"
+			return
+		}
+		t.request("startGame", nil)
+	}
+}
+
+func (t *tui) typeKey(key string) {
+	switch key {
+	case keyEsc:
+		t.typing = false
+	case keyEnter:
+		t.typing = false
+		if msg := strings.TrimSpace(string(t.input)); msg != "" {
+			t.request("sendChat", api.ChatRequest{Message: msg})
+		}
+	case keyBack:
+		if len(t.input) > 0 {
+			t.input = t.input[:len(t.input)-1]
+		}
+	case keyUp, keyDown, keyLeft, keyRight, keyTab:
+	default:
+		t.input = append(t.input, []rune(key)...)
+	}
+}
+
+// rows of the scorecard the cursor can be on (without sums)
+func (t *tui) rows() []string {
+	var rows []string
+	for _, row := range t.room.Rows {
+		if !strings.HasPrefix(row, "sum") {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func (t *tui) me() *api.Player {
+	for i := range t.room.Players {
+		if t.room.Players[i].ID == t.c.playerID {
+			return &t.room.Players[i]
+		}
+	}
+	return nil
+}
+
+// client-side checks, the server checks again but the player gets the
+// answer without a round trip
+
+func (t *tui) myTurn() error {
+	if t.room.State != string(game.StatePlaying) {
+		return errors.New("the game is not running")
+	}
+	if t.room.CurrentPlayerID != t.c.playerID {
+		return game.ErrNotYourTurn
+	}
+	return nil
+}
+
+func (t *tui) canRoll() error {
+	if err := t.myTurn(); err != nil {
+		return err
+	}
+	if t.room.Roll.RollsLeft == 0 {
+		return errors.New("no rolls left")
+	}
+	return nil
+}
+
+func (t *tui) canHold(die int) error {
+	if err := t.myTurn(); err != nil {
+		return err
+	}
+	if die >= len(t.room.Roll.Held) {
+		return errors.New("no such die")
+	}
+	if t.room.Roll.RollsLeft == 3 {
+		return errors.New("roll first")
+	}
+	return nil
+}
+
+func (t *tui) canSelect(row, col string) error {
+	if err := t.myTurn(); err != nil {
+		return err
+	}
+	sc := t.scoreCard(*t.me())
+	return sc.SelectCell(row, col)
+}
+
+// preview returns the score writing to the selected cell would give
+func (t *tui) preview() (int, error) {
+	if err := t.myTurn(); err != nil {
+		return 0, err
+	}
+	if t.room.Roll.RollsLeft == 3 {
+		return 0, errors.New("roll first")
+	}
+	me := t.me()
+	if me.Selected == nil {
+		return 0, errors.New("select a cell first (enter)")
+	}
+	sc := t.scoreCard(*me)
+	dice := &game.Dice{
+		Values:    slices.Clone(t.room.Roll.Values),
+		Held:      slices.Clone(t.room.Roll.Held),
+		RollsLeft: t.room.Roll.RollsLeft,
+	}
+	return sc.FillCell(me.Selected.Row, me.Selected.Col, dice)
+}
+
+// scoreCard rebuilds the scorecard of the player with the game package,
+// changing it does not change the player
+func (t *tui) scoreCard(p api.Player) game.ScoreCard {
+	sc := game.NewScoreCard(t.room.Rules)
+	for row, cols := range p.Scores {
+		sc.Scores[row] = maps.Clone(cols)
+	}
+	if p.Selected != nil {
+		sc.SelectedCell = [2]string{p.Selected.Row, p.Selected.Col}
+	}
+	sc.Announced = p.Announced
+	return sc
+}
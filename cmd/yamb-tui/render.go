@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"yamb/api"
+	"yamb/game"
+)
+
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	reverse     = "\x1b[7m"
+	bold        = "\x1b[1m"
+	dim         = "\x1b[2m"
+	reset       = "\x1b[0m"
+
+	// width of a scorecard column
+	cellWidth = 6
+	// chat messages shown
+	chatLines = 5
+)
+
+// render draws the whole screen, it is small enough to not bother with diffs
+func (t *tui) render(w io.Writer) {
+	var b strings.Builder
+	b.WriteString(clearScreen)
+
+	room := t.room
+	fmt.Fprintf(&b, "%syamb%s  room %s  %s  %s, %d dice, %s\n\n",
+		bold, reset, t.roomID, stateName(room.State), room.Mode, room.Dice, room.Rules)
+
+	t.renderPlayers(&b)
+	b.WriteString("\n")
+	if player, ok := t.shown(); ok {
+		t.renderScoreCard(&b, player)
+		b.WriteString("\n")
+	}
+	t.renderDice(&b)
+	b.WriteString("\n")
+	t.renderChat(&b)
+
+	b.WriteString("\n")
+	if t.typing {
+		fmt.Fprintf(&b, "say: %s_\n", string(t.input))
+	} else if t.status != "" {
+		fmt.Fprintf(&b, "%s\n", t.status)
+	} else {
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "%sarrows move  enter select  1-6 keep  r roll  a announce  w write  c chat  tab cards  R ready  s start  q quit%s\n", dim, reset)
+
+	io.WriteString(w, b.String())
+}
+
+func (t *tui) renderPlayers(b *strings.Builder) {
+	for _, p := range t.room.Players {
+		marker := "  "
+		if p.ID == t.room.CurrentPlayerID {
+			marker = "> "
+		}
+		var notes []string
+		if p.ID == t.c.playerID {
+			notes = append(notes, "you")
+		}
+		if p.ID == t.room.HostID {
+			notes = append(notes, "host")
+		}
+		if t.room.State == string(game.StateWaiting) || t.room.State == string(game.StateReady) {
+			if p.Ready {
+				notes = append(notes, "ready")
+			} else {
+				notes = append(notes, "not ready")
+			}
+		}
+		if !t.online[p.ID] {
+			notes = append(notes, "offline")
+		}
+		team := ""
+		if t.room.Mode == game.Mode2v2 {
+			team = fmt.Sprintf(" team %d", p.Team+1)
+		}
+		fmt.Fprintf(b, "%s%-16s %4d%s  %s\n", marker, p.Username, p.Total, team, strings.Join(notes, ", "))
+	}
+}
+
+// shown returns the player whose scorecard is on screen
+func (t *tui) shown() (api.Player, bool) {
+	n := len(t.room.Players)
+	if n == 0 {
+		return api.Player{}, false
+	}
+	idx := slices.IndexFunc(t.room.Players, func(p api.Player) bool { return p.ID == t.c.playerID })
+	idx = (max(idx, 0) + t.view) % n
+	return t.room.Players[idx], true
+}
+
+func (t *tui) renderScoreCard(b *strings.Builder, player api.Player) {
+	own := player.ID == t.c.playerID
+	sc := t.scoreCard(player)
+	if !own {
+		fmt.Fprintf(b, "scorecard of %s\n", player.Username)
+	}
+
+	fmt.Fprintf(b, "%-10s", "")
+	for _, col := range sc.Columns {
+		fmt.Fprintf(b, "%*s", cellWidth, col.Name)
+	}
+	b.WriteString("\n")
+
+	rows := t.rows()
+	for _, row := range sc.Rows {
+		sum := strings.HasPrefix(row.ID, "sum")
+		label := row.Name
+		if sum {
+			label = dim + fmt.Sprintf("%-10s", label) + reset
+		} else {
+			label = fmt.Sprintf("%-10s", label)
+		}
+		b.WriteString(label)
+		for _, col := range sc.Columns {
+			cell := "."
+			if score := sc.Scores[row.ID][col.ID]; score != nil {
+				cell = fmt.Sprint(*score)
+			}
+			if row.ID == sc.SelectedCell[0] && col.ID == sc.SelectedCell[1] {
+				cell = "[" + cell + "]"
+				if sc.Announced {
+					cell = "!" + cell
+				}
+			}
+			cell = fmt.Sprintf("%*s", cellWidth, cell)
+			cursor := own && !sum && t.row < len(rows) && t.col < len(t.room.Columns) &&
+				rows[t.row] == row.ID && t.room.Columns[t.col] == col.ID
+			if cursor {
+				cell = reverse + cell + reset
+			}
+			b.WriteString(cell)
+		}
+		b.WriteString("\n")
+	}
+
+	if own {
+		if score, err := t.preview(); err == nil {
+			fmt.Fprintf(b, "writing now gives %d\n", score)
+		}
+	}
+}
+
+func (t *tui) renderDice(b *strings.Builder) {
+	roll := t.room.Roll
+	if t.room.State != string(game.StatePlaying) && t.room.State != string(game.StatePaused) {
+		return
+	}
+	b.WriteString("dice  ")
+	for i, v := range roll.Values {
+		if i < len(roll.Held) && roll.Held[i] {
+			fmt.Fprintf(b, "%s[%d]%s ", bold, v, reset)
+		} else {
+			fmt.Fprintf(b, " %d  ", v)
+		}
+	}
+	fmt.Fprintf(b, "  rolls left %d\n", roll.RollsLeft)
+}
+
+func (t *tui) renderChat(b *strings.Builder) {
+	from := max(len(t.chat)-chatLines, 0)
+	for _, msg := range t.chat[from:] {
+		fmt.Fprintf(b, "%s%s:%s %s\n", bold, msg.Username, reset, msg.Message)
+	}
+}
+
+func stateName(state string) string {
+	if state == "" {
+		return "connecting"
+	}
+	return state
+}
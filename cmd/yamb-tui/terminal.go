@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// keys that are not a single character
+const (
+	keyUp    = "up"
+	keyDown  = "down"
+	keyLeft  = "left"
+	keyRight = "right"
+	keyEnter = "enter"
+	keyEsc   = "esc"
+	keyBack  = "backspace"
+	keyTab   = "tab"
+)
+
+// rawMode turns off line buffering and echo of the terminal with stty,
+// the returned function restores the terminal
+func rawMode() (func(), error) {
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil, err
+	}
+	return func() {
+		stty(strings.TrimSpace(saved))
+	}, nil
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+// readKeys sends the pressed keys to keys until stdin is closed
+func readKeys(keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, key := range parseKeys(string(buf[:n])) {
+			keys <- key
+		}
+	}
+}
+
+var escapes = map[string]string{
+	"\x1b[A": keyUp,
+	"\x1b[B": keyDown,
+	"\x1b[C": keyRight,
+	"\x1b[D": keyLeft,
+	"\x1bOA": keyUp,
+	"\x1bOB": keyDown,
+	"\x1bOC": keyRight,
+	"\x1bOD": keyLeft,
+}
+
+// parseKeys splits what one read returned into keys, escape sequences of the
+// arrow keys arrive together
+func parseKeys(s string) []string {
+	var keys []string
+	for len(s) > 0 {
+		if s[0] == '\x1b' {
+			found := false
+			for seq, key := range escapes {
+				if strings.HasPrefix(s, seq) {
+					keys = append(keys, key)
+					s = s[len(seq):]
+					found = true
+					break
+				}
+			}
+			if !found {
+				keys = append(keys, keyEsc)
+				s = s[1:]
+			}
+			continue
+		}
+		r := []rune(s)[0]
+		switch r {
+		case '\r', '\n':
+			keys = append(keys, keyEnter)
+		case '\t':
+			keys = append(keys, keyTab)
+		case 127, '\b':
+			keys = append(keys, keyBack)
+		default:
+			keys = append(keys, string(r))
+		}
+		s = s[len(string(r)):]
+	}
+	return keys
+}
@@ -0,0 +1,62 @@
+// yamb-verify checks exported game records: every move is replayed through
+// the game rules and the recorded scorecards are compared with the replay.
+//
+//	yamb-verify game.json [more.json ...]
+//	yamb-verify < game.json
+//
+// Exits with 1 if any record has problems.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"yamb/record"
+)
+
+func main() {
+	files := os.Args[1:]
+	if len(files) == 0 {
+		// read a single record from stdin
+		files = []string{"-"}
+	}
+
+	ok := true
+	for _, name := range files {
+		if !verify(name) {
+			ok = false
+		}
+	}
+	if !ok {
+		os.Exit(1)
+	}
+}
+
+func verify(name string) bool {
+	var in io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return false
+		}
+		defer f.Close()
+		in = f
+	}
+
+	rec, err := record.Read(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return false
+	}
+	rep := record.Verify(rec)
+	if rep.OK() {
+		fmt.Printf("%s: ok (%d moves)\n", name, rep.Moves)
+		return true
+	}
+	fmt.Printf("%s: not valid (%d legal moves)\n", name, rep.Moves)
+	for _, p := range rep.Problems {
+		fmt.Println("  " + p)
+	}
+	return false
+}
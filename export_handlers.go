@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"yamb/record"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ExportJSONHandler downloads the finished game as a JSON game record
+func ExportJSONHandler(w http.ResponseWriter, r *http.Request) {
+	exportRecord(w, r, "json", "application/json", (*record.Record).WriteJSON)
+}
+
+// ExportCSVHandler downloads the scorecards of the finished game as CSV
+func ExportCSVHandler(w http.ResponseWriter, r *http.Request) {
+	exportRecord(w, r, "csv", "text/csv", (*record.Record).WriteCSV)
+}
+
+func exportRecord(w http.ResponseWriter, r *http.Request, ext, contentType string, write func(*record.Record, io.Writer) error) {
+	roomID := chi.URLParam(r, "roomID")
+	room, ok := findRoom(roomID)
+	if !ok {
+		HxError(w, "room does not exist", 404)
+		return
+	}
+
+	if !canAccessRoom(r, room) {
+		HxError(w, "private room", http.StatusForbidden)
+		return
+	}
+
+	rec, err := record.New(roomID, room.Events())
+	if errors.Is(err, record.ErrNotFinished) {
+		HxError(w, "game is not finished", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		HxError(w, "could not create game record", http.StatusInternalServerError)
+		log.Println("error creating game record:", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="yamb-%s.%s"`, roomID, ext))
+	err = write(rec, w)
+	if err != nil {
+		log.Println("error writing game record:", err)
+		return
+	}
+}
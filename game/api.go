@@ -0,0 +1,81 @@
+package game
+
+import (
+	"maps"
+	"slices"
+	"yamb/api"
+)
+
+// APIRoom describes the room for the JSON API
+func (r *Room) APIRoom() api.Room {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	room := api.Room{
+		ID:        r.ID,
+		State:     string(r.State),
+		Mode:      r.Mode,
+		Dice:      r.NumOfDice,
+		Rules:     r.Rules,
+		TurnOrder: r.TurnOrder,
+		Private:   r.PassphraseHash != nil,
+		HostID:    r.HostID,
+		Rows:      []string{},
+		Columns:   []string{},
+		Roll: api.Roll{
+			Values:    slices.Clone(r.Dice.Values),
+			Held:      slices.Clone(r.Dice.Held),
+			RollsLeft: r.Dice.RollsLeft,
+		},
+		Players: make([]api.Player, len(r.Players)),
+	}
+	if r.State == StatePlaying || r.State == StatePaused {
+		room.CurrentPlayerID = r.currentPlayerID()
+	}
+	// every scorecard of the room has the same layout
+	sc := NewScoreCard(r.Rules)
+	for _, row := range sc.Rows {
+		room.Rows = append(room.Rows, row.ID)
+	}
+	for _, col := range sc.Columns {
+		room.Columns = append(room.Columns, col.ID)
+	}
+	for i, p := range r.Players {
+		player := api.Player{
+			ID:        p.ID,
+			Username:  p.Username,
+			Team:      int(p.Team),
+			Ready:     p.Ready,
+			Total:     p.ScoreCard.TotalScore(),
+			Scores:    make(map[string]map[string]*int, len(p.ScoreCard.Scores)),
+			Announced: p.ScoreCard.IsAnnounced(),
+		}
+		for row, cols := range p.ScoreCard.Scores {
+			player.Scores[row] = maps.Clone(cols)
+		}
+		if row, col := p.ScoreCard.GetSelectedCell(); row != "" {
+			player.Selected = &api.Cell{Row: row, Col: col}
+		}
+		room.Players[i] = player
+	}
+	return room
+}
+
+// APIChat returns the chat history for the JSON API
+func (r *Room) APIChat() []api.ChatMessage {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	messages := make([]api.ChatMessage, 0, len(r.ChatHistory))
+	for _, msg := range r.ChatHistory {
+		username := ""
+		if idx := r.playerIndex(msg.PlayerID); idx != -1 {
+			username = r.Players[idx].Username
+		}
+		messages = append(messages, api.ChatMessage{
+			PlayerID: msg.PlayerID,
+			Username: username,
+			Message:  msg.Message,
+			SentAt:   msg.SentAt,
+		})
+	}
+	return messages
+}
@@ -2,6 +2,7 @@ package game
 
 import (
 	"errors"
+	"math/rand"
 	"slices"
 )
 
@@ -25,6 +26,27 @@ func NewDice(num int) *Dice {
 	}
 }
 
+// Roll rolls every die that is not held
+func (d *Dice) Roll() error {
+	if d.RollsLeft == 0 {
+		return errors.New("no rolls left")
+	}
+	d.Values = d.next()
+	d.RollsLeft--
+	return nil
+}
+
+// next returns the values after a roll without changing the dice
+func (d *Dice) next() []int {
+	values := slices.Clone(d.Values)
+	for i := range values {
+		if !d.Held[i] {
+			values[i] = 1 + rand.Intn(6)
+		}
+	}
+	return values
+}
+
 func (d *Dice) ToggleDie(index int) {
 	if index >= 0 && index < len(d.Held) {
 		d.Held[index] = !d.Held[index]
@@ -0,0 +1,253 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// every change to a game is recorded as an event in Room.Log, the game can be
+// rebuilt by replaying the log (see Replay)
+
+type EventType string
+
+const (
+	EventStarted   EventType = "started"   // settings and turn order of a new game
+	EventRolled    EventType = "rolled"    // dice after the roll
+	EventToggled   EventType = "toggled"   // die kept or released
+	EventSelected  EventType = "selected"  // cell (un)selected
+	EventAnnounced EventType = "announced" // player announced the selected cell
+	EventWritten   EventType = "written"   // score written, ends the turn
+	EventPaused    EventType = "paused"    // paused by vote or an adjourned game came back
+	EventResumed   EventType = "resumed"   // play continues
+	EventAdjourned EventType = "adjourned"
+	EventAbandoned EventType = "abandoned"
+)
+
+// player at a seat when the game started
+type Seat struct {
+	ID        string
+	Username  string
+	Team      Team
+	AccountID string `json:",omitempty"`
+}
+
+type GameEvent struct {
+	Seq      int
+	At       time.Time
+	Type     EventType
+	PlayerID string `json:",omitempty"`
+
+	// started
+	Mode      string         `json:",omitempty"`
+	Rules     string         `json:",omitempty"`
+	NumOfDice int            `json:",omitempty"`
+	Seats     []Seat         `json:",omitempty"`
+	RollOff   []RollOffRound `json:",omitempty"`
+
+	// dice values and kept dice (rolled, written)
+	Dice []int  `json:",omitempty"`
+	Held []bool `json:",omitempty"`
+	// toggled die
+	Die int `json:",omitempty"`
+	// selected, written
+	Row   string `json:",omitempty"`
+	Col   string `json:",omitempty"`
+	Score int    `json:",omitempty"`
+}
+
+// record applies the event and appends it to the log, caller must hold the lock
+func (r *Room) record(ev GameEvent) error {
+	ev.Seq = len(r.Log) + 1
+	ev.At = time.Now()
+	if err := r.apply(ev); err != nil {
+		return err
+	}
+	r.Log = append(r.Log, ev)
+	return nil
+}
+
+// apply changes the room as described by the event, the rules are checked
+// again so that replaying a log from elsewhere catches illegal moves,
+// caller must hold the lock
+func (r *Room) apply(ev GameEvent) error {
+	switch ev.Type {
+	case EventStarted:
+		return r.start(ev)
+	case EventPaused:
+		return r.transition(StatePaused)
+	case EventResumed:
+		return r.transition(StatePlaying)
+	case EventAdjourned:
+		return r.transition(StateAdjourned)
+	case EventAbandoned:
+		return r.transition(StateAbandoned)
+	}
+
+	// the rest are moves of the player whose turn it is
+	player, err := r.checkTurn(ev.PlayerID)
+	if err != nil {
+		return err
+	}
+
+	switch ev.Type {
+	case EventRolled:
+		if r.Dice.RollsLeft == 0 {
+			return errors.New("no rolls left")
+		}
+		if len(ev.Dice) != len(r.Dice.Values) {
+			return fmt.Errorf("rolled %d dice, room has %d", len(ev.Dice), len(r.Dice.Values))
+		}
+		for i, v := range ev.Dice {
+			if v < 1 || v > 6 {
+				return fmt.Errorf("die %d rolled %d", i, v)
+			}
+			if r.Dice.Held[i] && v != r.Dice.Values[i] {
+				return fmt.Errorf("kept die %d changed from %d to %d", i, r.Dice.Values[i], v)
+			}
+		}
+		r.Dice.Values = slices.Clone(ev.Dice)
+		r.Dice.RollsLeft--
+	case EventToggled:
+		if r.Dice.RollsLeft == 3 {
+			return errors.New("cannot keep dice before the first roll")
+		}
+		if ev.Die < 0 || ev.Die >= len(r.Dice.Held) {
+			return errors.New("no such die")
+		}
+		r.Dice.ToggleDie(ev.Die)
+	case EventSelected:
+		return player.ScoreCard.SelectCell(ev.Row, ev.Col)
+	case EventAnnounced:
+		player.ScoreCard.Announce()
+	case EventWritten:
+		// whatever rejects the event is checked before the room changes, a
+		// rejected event leaves the room as it was
+		score, err := player.ScoreCard.CalculateScore(ev.Row, r.Dice)
+		if err != nil {
+			return err
+		}
+		if score != ev.Score {
+			return fmt.Errorf("score %d does not match the recorded %d", score, ev.Score)
+		}
+		if !r.State.CanTransitionTo(StateFinished) {
+			return fmt.Errorf("cannot go from %s to %s", r.State, StateFinished)
+		}
+		if _, err := player.ScoreCard.FillCell(ev.Row, ev.Col, r.Dice); err != nil {
+			return err
+		}
+		player.ScoreCard.CalculateSums()
+		player.ScoreCard.UnselectCell()
+		r.endTurn()
+		if r.allComplete() {
+			return r.transition(StateFinished)
+		}
+	default:
+		return fmt.Errorf("unknown event %q", ev.Type)
+	}
+	return nil
+}
+
+// start seats the players in the recorded turn order with empty scorecards,
+// caller must hold the lock
+func (r *Room) start(ev GameEvent) error {
+	numOfPlayers, _, _, err := parseSettings(ev.Mode, strconv.Itoa(ev.NumOfDice), ev.Rules)
+	if err != nil {
+		return err
+	}
+	if len(ev.Seats) != numOfPlayers {
+		return fmt.Errorf("%s needs %d players, got %d", ev.Mode, numOfPlayers, len(ev.Seats))
+	}
+
+	players := make([]*Player, len(ev.Seats))
+	for i, seat := range ev.Seats {
+		var p *Player
+		if idx := r.playerIndex(seat.ID); idx != -1 {
+			p = r.Players[idx]
+		} else {
+			p = NewPlayer(seat.ID, seat.Username)
+			p.AccountID = seat.AccountID
+		}
+		p.Team = seat.Team
+		p.ScoreCard = NewScoreCard(ev.Rules)
+		players[i] = p
+	}
+	r.Players = players
+	r.Mode = ev.Mode
+	r.Rules = ev.Rules
+	r.NumOfDice = ev.NumOfDice
+	r.NumOfPlayers = len(players)
+	r.RollOff = ev.RollOff
+	r.CurrentTurn = 0
+	r.TurnsPlayed = 0
+	r.Dice = NewDice(ev.NumOfDice)
+	// a new game can start from any state (lobby, rematch or an empty replay)
+	r.State = StatePlaying
+	return nil
+}
+
+// startedEvent describes the game about to start with the current players,
+// caller must hold the lock
+func (r *Room) startedEvent(playerID string) GameEvent {
+	seats := make([]Seat, len(r.Players))
+	for i, p := range r.Players {
+		seats[i] = Seat{ID: p.ID, Username: p.Username, Team: p.Team, AccountID: p.AccountID}
+	}
+	return GameEvent{
+		Type:      EventStarted,
+		PlayerID:  playerID,
+		Mode:      r.Mode,
+		Rules:     r.Rules,
+		NumOfDice: r.NumOfDice,
+		Seats:     seats,
+		RollOff:   r.RollOff,
+	}
+}
+
+// ReplayError tells which event of the log could not be replayed
+type ReplayError struct {
+	Index int // in the replayed log
+	Event GameEvent
+	Err   error
+}
+
+func (e *ReplayError) Error() string {
+	return fmt.Sprintf("event %d (%s): %v", e.Event.Seq, e.Event.Type, e.Err)
+}
+
+func (e *ReplayError) Unwrap() error {
+	return e.Err
+}
+
+// Replay rebuilds a room from its event log, the log may end in the middle
+// of a game (e.g. to show a game move by move)
+func Replay(log []GameEvent) (*Room, error) {
+	r := NewRoom(Mode1v1, "6", RulesClassic)
+	for i, ev := range log {
+		if err := r.apply(ev); err != nil {
+			return nil, &ReplayError{Index: i, Event: ev, Err: err}
+		}
+	}
+	r.Log = slices.Clone(log)
+	return r, nil
+}
+
+// Events returns a copy of the event log
+func (r *Room) Events() []GameEvent {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return slices.Clone(r.Log)
+}
+
+// LastGame returns the events of the most recent game in the log, nil if no
+// game was started yet
+func LastGame(log []GameEvent) []GameEvent {
+	for i := len(log) - 1; i >= 0; i-- {
+		if log[i].Type == EventStarted {
+			return log[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package game
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// playTurn rolls once and writes the first cell the dice allow
+func playTurn(t *testing.T, r *Room) {
+	t.Helper()
+	if err := r.RollDice(r.CurrentPlayerID()); err != nil {
+		t.Fatal(err)
+	}
+	finishTurn(t, r)
+}
+
+// finishTurn writes the first cell the rolled dice allow
+func finishTurn(t *testing.T, r *Room) {
+	t.Helper()
+	playerID := r.CurrentPlayerID()
+	p := r.GetPlayerByID(playerID)
+	for _, col := range p.ScoreCard.Columns {
+		for _, row := range p.ScoreCard.Rows {
+			if p.ScoreCard.Scores[row.ID][col.ID] == nil && writeCell(r, playerID, row.ID, col.ID) {
+				return
+			}
+		}
+	}
+	t.Fatalf("%s cannot write anything", playerID)
+}
+
+// writeCell holds the dice the row needs and writes the cell
+func writeCell(r *Room, playerID, row, col string) bool {
+	if r.SelectCell(playerID, row, col) != nil {
+		return false
+	}
+	if col == Announced && r.Announce(playerID) != nil {
+		return false
+	}
+	want := func(i, _ int) bool { return i < 5 }
+	if n, err := strconv.Atoi(row); err == nil {
+		want = func(_, v int) bool { return v == n }
+	} else if row == Quads {
+		want = func(i, _ int) bool { return i < 4 }
+	}
+	for i, v := range r.Dice.Values {
+		if r.Dice.Held[i] != want(i, v) {
+			r.ToggleDie(playerID, i)
+		}
+	}
+	_, err := r.WriteScore(playerID)
+	return err == nil
+}
+
+// sameGame compares what the event log decides about two rooms
+func sameGame(t *testing.T, name string, live, replayed *Room) {
+	t.Helper()
+	if replayed.State != live.State || replayed.CurrentTurn != live.CurrentTurn || replayed.TurnsPlayed != live.TurnsPlayed {
+		t.Errorf("%s: replay is %s, turn %d of %d played, live room %s, turn %d of %d played", name,
+			replayed.State, replayed.CurrentTurn, replayed.TurnsPlayed, live.State, live.CurrentTurn, live.TurnsPlayed)
+	}
+	if !reflect.DeepEqual(replayed.Dice, live.Dice) {
+		t.Errorf("%s: replayed dice %+v, live dice %+v", name, replayed.Dice, live.Dice)
+	}
+	if len(replayed.Players) != len(live.Players) {
+		t.Fatalf("%s: replay has %d players, live room %d", name, len(replayed.Players), len(live.Players))
+	}
+	for i, p := range live.Players {
+		got := replayed.Players[i]
+		if got.ID != p.ID || !reflect.DeepEqual(got.ScoreCard, p.ScoreCard) {
+			t.Errorf("%s: player %d differs after replay", name, i)
+		}
+	}
+}
+
+func TestReplayRebuildsRoom(t *testing.T) {
+	r, _ := newGame(t)
+
+	tests := []struct {
+		name string
+		play func()
+	}{
+		{"started", func() {}},
+		{"rolled", func() { r.RollDice(r.CurrentPlayerID()) }},
+		{"kept a die", func() { r.ToggleDie(r.CurrentPlayerID(), 2) }},
+		{"selected", func() { r.SelectCell(r.CurrentPlayerID(), Yamb, Free) }},
+		{"written", func() { finishTurn(t, r) }},
+		{"ten turns", func() {
+			for range 10 {
+				playTurn(t, r)
+			}
+		}},
+		{"finished", func() {
+			for r.GetState() == StatePlaying {
+				playTurn(t, r)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		tt.play()
+		replayed, err := Replay(r.Events())
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		sameGame(t, tt.name, r, replayed)
+	}
+	if r.GetState() != StateFinished {
+		t.Errorf("game ended in state %s", r.GetState())
+	}
+}
+
+func TestReplayRejectsIllegalEvents(t *testing.T) {
+	r, players := newGame(t)
+	playTurn(t, r)
+	current, other := r.CurrentPlayerID(), players[0].ID
+	if other == current {
+		other = players[1].ID
+	}
+
+	tests := []struct {
+		name string
+		ev   GameEvent
+	}{
+		{"off turn", GameEvent{Type: EventRolled, PlayerID: other, Dice: []int{1, 2, 3, 4, 5, 6}}},
+		{"stranger", GameEvent{Type: EventRolled, PlayerID: "nobody", Dice: []int{1, 2, 3, 4, 5, 6}}},
+		{"keep before rolling", GameEvent{Type: EventToggled, PlayerID: current, Die: 0}},
+		{"write without a cell", GameEvent{Type: EventWritten, PlayerID: current}},
+	}
+	for _, tt := range tests {
+		log := append(r.Events(), tt.ev)
+		_, err := Replay(log)
+		replayErr, ok := err.(*ReplayError)
+		if !ok {
+			t.Errorf("%s: Replay = %v, want a ReplayError", tt.name, err)
+			continue
+		}
+		if replayErr.Index != len(log)-1 {
+			t.Errorf("%s: Replay failed at event %d, want %d", tt.name, replayErr.Index, len(log)-1)
+		}
+	}
+}
+
+// an event with the wrong score is refused and leaves the scorecard as it was
+func TestRejectedWriteLeavesRoom(t *testing.T) {
+	r, _ := newGame(t)
+	playerID := r.CurrentPlayerID()
+	if err := r.RollDice(playerID); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SelectCell(playerID, Max, Free); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 5 {
+		if err := r.ToggleDie(playerID, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	score, err := r.GetPlayerByID(playerID).ScoreCard.CalculateScore(Max, r.Dice)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := len(r.Events())
+	err = r.record(GameEvent{Type: EventWritten, PlayerID: playerID, Row: Max, Col: Free, Score: score + 1})
+	if err == nil {
+		t.Fatal("a wrong score was written")
+	}
+	if cell := r.GetPlayerByID(playerID).ScoreCard.Scores[Max][Free]; cell != nil {
+		t.Errorf("refused write filled the cell with %d", *cell)
+	}
+	if len(r.Events()) != events || r.CurrentPlayerID() != playerID {
+		t.Error("refused write ended the turn")
+	}
+	if _, err := r.WriteScore(playerID); err != nil {
+		t.Errorf("the cell cannot be written after the refused write: %v", err)
+	}
+}
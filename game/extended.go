@@ -0,0 +1,73 @@
+package game
+
+import (
+	"errors"
+	"slices"
+)
+
+// columns of the extended ruleset, both start or end at the max and min rows
+// in the middle of the scorecard instead of at its edges
+
+// rows that players fill (without sums), in order from top to bottom
+func (sc *ScoreCard) playableRows() []string {
+	rows := []string{}
+	for _, r := range sc.Rows {
+		if len(r.ID) >= 3 && r.ID[:3] == "sum" {
+			continue
+		}
+		rows = append(rows, r.ID)
+	}
+	return rows
+}
+
+// upper section (numbers and max) is filled upwards from max, lower section
+// (min and below) downwards from min
+func (sc *ScoreCard) fillMiddleToTopAndBottom(rowID string, score int) error {
+	rows := sc.playableRows()
+	for i, id := range rows {
+		if id != rowID {
+			continue
+		}
+		switch {
+		case id == Max || id == Min:
+			// middle rows, no need to check anything
+		case i < slices.Index(rows, Max):
+			if sc.Scores[rows[i+1]][MiddleToTopAndToBottom] == nil {
+				return errors.New("field below is not filled")
+			}
+		default:
+			if sc.Scores[rows[i-1]][MiddleToTopAndToBottom] == nil {
+				return errors.New("field above is not filled")
+			}
+		}
+		sc.Scores[rowID][MiddleToTopAndToBottom] = &score
+		return nil
+	}
+	return errors.New("unknown row ID")
+}
+
+// upper section is filled downwards from the top to max, lower section
+// upwards from the bottom to min
+func (sc *ScoreCard) fillTopAndBottomToMiddle(rowID string, score int) error {
+	rows := sc.playableRows()
+	for i, id := range rows {
+		if id != rowID {
+			continue
+		}
+		switch {
+		case i == 0 || i == len(rows)-1:
+			// first and last row, no need to check anything
+		case i <= slices.Index(rows, Max):
+			if sc.Scores[rows[i-1]][TopAndBottomToMiddle] == nil {
+				return errors.New("field above is not filled")
+			}
+		default:
+			if sc.Scores[rows[i+1]][TopAndBottomToMiddle] == nil {
+				return errors.New("field below is not filled")
+			}
+		}
+		sc.Scores[rowID][TopAndBottomToMiddle] = &score
+		return nil
+	}
+	return errors.New("unknown row ID")
+}
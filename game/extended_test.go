@@ -0,0 +1,69 @@
+package game
+
+import "testing"
+
+func TestExtendedColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		col     string
+		filled  []string // rows already written in the column
+		row     string
+		wantErr bool
+	}{
+		{"middle: max first", MiddleToTopAndToBottom, nil, Max, false},
+		{"middle: min first", MiddleToTopAndToBottom, nil, Min, false},
+		{"middle: sixes before max", MiddleToTopAndToBottom, nil, Sixes, true},
+		{"middle: sixes after max", MiddleToTopAndToBottom, []string{Max}, Sixes, false},
+		{"middle: ones skipping twos", MiddleToTopAndToBottom, []string{Max, Sixes, Fives, Fours, Threes}, Ones, true},
+		{"middle: ones last", MiddleToTopAndToBottom, []string{Max, Sixes, Fives, Fours, Threes, Twos}, Ones, false},
+		{"middle: straight before min", MiddleToTopAndToBottom, []string{Max}, Straight, true},
+		{"middle: straight after min", MiddleToTopAndToBottom, []string{Min}, Straight, false},
+		{"middle: yamb last", MiddleToTopAndToBottom, []string{Min, Straight, FullHouse, Quads}, Yamb, false},
+		{"middle: sum row", MiddleToTopAndToBottom, nil, Sum1, true},
+
+		{"edges: ones first", TopAndBottomToMiddle, nil, Ones, false},
+		{"edges: yamb first", TopAndBottomToMiddle, nil, Yamb, false},
+		{"edges: twos before ones", TopAndBottomToMiddle, nil, Twos, true},
+		{"edges: twos after ones", TopAndBottomToMiddle, []string{Ones}, Twos, false},
+		{"edges: max before sixes", TopAndBottomToMiddle, []string{Ones, Twos, Threes, Fours, Fives}, Max, true},
+		{"edges: max last", TopAndBottomToMiddle, []string{Ones, Twos, Threes, Fours, Fives, Sixes}, Max, false},
+		{"edges: quads before yamb", TopAndBottomToMiddle, nil, Quads, true},
+		{"edges: min last", TopAndBottomToMiddle, []string{Yamb, Quads, FullHouse, Straight}, Min, false},
+		{"edges: min from the top", TopAndBottomToMiddle, []string{Ones, Twos, Threes, Fours, Fives, Sixes, Max}, Min, true},
+	}
+	for _, tt := range tests {
+		sc := NewScoreCard(RulesExtended)
+		score := 10
+		for _, row := range tt.filled {
+			sc.Scores[row][tt.col] = &score
+		}
+
+		var err error
+		if tt.col == MiddleToTopAndToBottom {
+			err = sc.fillMiddleToTopAndBottom(tt.row, 20)
+		} else {
+			err = sc.fillTopAndBottomToMiddle(tt.row, 20)
+		}
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if got := sc.Scores[tt.row][tt.col]; err == nil && (got == nil || *got != 20) {
+			t.Errorf("%s: cell holds %v, want 20", tt.name, got)
+		}
+	}
+}
+
+// classic scorecards have no middle columns to write to
+func TestClassicHasNoExtendedColumns(t *testing.T) {
+	sc := NewScoreCard(RulesClassic)
+	for _, col := range sc.Columns {
+		if col.ID == MiddleToTopAndToBottom || col.ID == TopAndBottomToMiddle {
+			t.Errorf("classic scorecard has column %s", col.ID)
+		}
+	}
+	dice := NewDice(6)
+	if _, err := sc.FillCell(Max, MiddleToTopAndToBottom, dice); err == nil {
+		t.Error("classic scorecard accepts a score in a middle column")
+	}
+}
@@ -0,0 +1,141 @@
+package game
+
+import (
+	"errors"
+	"slices"
+)
+
+// moderation actions available to the host of the room
+
+func (r *Room) IsHost(playerID string) bool {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return r.HostID != "" && r.HostID == playerID
+}
+
+// Kick removes a player from the room, only possible before the game starts
+func (r *Room) Kick(playerID string) error {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	if !r.inLobby() {
+		return ErrNotInLobby
+	}
+	if playerID == r.HostID {
+		return errors.New("host cannot be kicked")
+	}
+	idx := r.playerIndex(playerID)
+	if idx == -1 {
+		return ErrNotInRoom
+	}
+	if hash := r.Players[idx].TokenHash; hash != "" {
+		r.Kicked[hash] = true
+	}
+	r.Players = slices.Delete(r.Players, idx, idx+1)
+	r.assignTeams()
+	r.updateReadiness()
+	return nil
+}
+
+// MovePlayer moves a player up (delta < 0) or down (delta > 0) in the turn order
+func (r *Room) MovePlayer(playerID string, delta int) error {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	if !r.inLobby() {
+		return ErrNotInLobby
+	}
+	idx := r.playerIndex(playerID)
+	if idx == -1 {
+		return ErrNotInRoom
+	}
+	newIdx := idx + delta
+	if newIdx < 0 || newIdx >= len(r.Players) {
+		return errors.New("cannot move player out of the turn order")
+	}
+	r.Players[idx], r.Players[newIdx] = r.Players[newIdx], r.Players[idx]
+	r.assignTeams()
+	return nil
+}
+
+// Configure changes the mode, dice count and ruleset while waiting for players
+func (r *Room) Configure(mode, dice, rules string) error {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return r.configure(mode, dice, rules, r.TurnOrder)
+}
+
+// UpdateSettings changes the mode, dice count, ruleset and turn order at
+// once, nothing changes when one of them is wrong
+func (r *Room) UpdateSettings(mode, dice, rules, turnOrder string) error {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return r.configure(mode, dice, rules, turnOrder)
+}
+
+// caller must hold the lock
+func (r *Room) configure(mode, dice, rules, turnOrder string) error {
+	numOfPlayers, numOfDice, rules, err := parseSettings(mode, dice, rules)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(TurnOrders(), turnOrder) {
+		return ErrUnknownTurnOrder
+	}
+	if !r.inLobby() {
+		return ErrNotInLobby
+	}
+	if len(r.Players) > numOfPlayers {
+		return errors.New("too many players for this mode")
+	}
+	if (mode == ModeSolo) != (r.Mode == ModeSolo) {
+		return errors.New("solo is chosen when creating the room")
+	}
+	r.Mode = mode
+	r.TurnOrder = turnOrder
+	r.NumOfPlayers = numOfPlayers
+	r.NumOfDice = numOfDice
+	r.Rules = rules
+	r.Dice = NewDice(numOfDice)
+	for _, p := range r.Players {
+		p.ScoreCard = NewScoreCard(rules)
+	}
+	// everyone has to agree to the new settings
+	r.unready()
+	r.updateReadiness()
+	return nil
+}
+
+func (r *Room) TransferHost(playerID string) error {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	idx := r.playerIndex(playerID)
+	if idx == -1 {
+		return ErrNotInRoom
+	}
+	if r.HotSeat {
+		// the other seats have no device to host from
+		return errors.New("hot-seat rooms keep their host")
+	}
+	r.HostID = playerID
+	r.HostToken = r.Players[idx].TokenHash
+	return nil
+}
+
+func (r *Room) Cancel() error {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return r.record(GameEvent{Type: EventAbandoned, PlayerID: r.HostID})
+}
+
+// caller must hold the lock
+func (r *Room) playerIndex(playerID string) int {
+	return slices.IndexFunc(r.Players, func(p *Player) bool {
+		return p.ID == playerID
+	})
+}
+
+// teams (colors) follow the seat in the turn order, caller must hold the lock
+func (r *Room) assignTeams() {
+	for i, p := range r.Players {
+		p.Team = Team(i)
+	}
+}
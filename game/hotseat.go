@@ -0,0 +1,59 @@
+package game
+
+import "errors"
+
+// hot-seat rooms: the host plays every seat on one device, the seats are
+// players without a browser of their own
+
+var ErrNotHotSeat = errors.New("not a hot-seat room")
+
+// Seat returns the player the session acts for, in hot-seat rooms the host
+// plays whoever is on turn
+func (r *Room) Seat(playerID string) string {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	if !r.HotSeat || playerID != r.HostID {
+		return playerID
+	}
+	if r.State == StatePlaying || r.State == StatePaused {
+		return r.currentPlayerID()
+	}
+	return playerID
+}
+
+// AddSeat adds a player who plays on the host's device, they are ready
+// whenever the host is
+func (r *Room) AddSeat(player *Player) error {
+	r.Mu.Lock()
+	hotSeat := r.HotSeat
+	r.Mu.Unlock()
+	if !hotSeat {
+		return ErrNotHotSeat
+	}
+	player.Ready = true
+	return r.AddPlayer(player)
+}
+
+// HandOverPending reports whether the device waits to be passed to the
+// player on turn, their scorecard stays hidden until they confirm
+func (r *Room) HandOverPending() bool {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return r.HotSeat && r.HandOver && r.State == StatePlaying
+}
+
+// ConfirmHandOver is called once the player on turn has the device
+func (r *Room) ConfirmHandOver() {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	r.HandOver = false
+}
+
+// unready makes the players confirm again (e.g. new settings), the seats on
+// the host's device follow the host and a solo player is always ready,
+// caller must hold the lock
+func (r *Room) unready() {
+	for _, p := range r.Players {
+		p.Ready = (r.HotSeat && p.ID != r.HostID) || r.Mode == ModeSolo
+	}
+}
@@ -0,0 +1,132 @@
+package game
+
+import "errors"
+
+var ErrPaused = errors.New("game is paused")
+
+// Vote registers the player's agreement to pause (to = StatePaused) or resume
+// (to = StatePlaying) the game, the room moves once every player agreed.
+// Reports whether the room moved.
+func (r *Room) Vote(playerID string, to State) (bool, error) {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	if r.playerIndex(playerID) == -1 {
+		return false, ErrNotInRoom
+	}
+	switch {
+	case to == StatePaused && r.State == StatePlaying:
+	case to == StatePlaying && r.State == StatePaused:
+	default:
+		return false, errors.New("nothing to vote on")
+	}
+
+	if r.VoteFor != to {
+		r.Votes = make(map[string]bool)
+		r.VoteFor = to
+	}
+	r.Votes[playerID] = true
+	if r.HotSeat && playerID == r.HostID {
+		// the host speaks for the seats on their device
+		for _, p := range r.Players {
+			r.Votes[p.ID] = true
+		}
+	}
+
+	for _, p := range r.Players {
+		if !r.Votes[p.ID] {
+			return false, nil
+		}
+	}
+	r.clearVote()
+	ev := GameEvent{Type: EventResumed, PlayerID: playerID}
+	if to == StatePaused {
+		ev.Type = EventPaused
+	}
+	return true, r.record(ev)
+}
+
+// DeclineVote cancels the pending pause/resume vote
+func (r *Room) DeclineVote(playerID string) error {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	if r.playerIndex(playerID) == -1 {
+		return ErrNotInRoom
+	}
+	r.clearVote()
+	return nil
+}
+
+// HasVoted reports whether the player agreed to the pending vote
+func (r *Room) HasVoted(playerID string) bool {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return r.Votes[playerID]
+}
+
+// caller must hold the lock
+func (r *Room) clearVote() {
+	r.Votes = nil
+	r.VoteFor = ""
+}
+
+// Adjourn stops a paused game so that it can be resumed later, the resume
+// links of the players work until the game plays again
+func (r *Room) Adjourn(playerID string) error {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	if r.playerIndex(playerID) == -1 {
+		return ErrNotInRoom
+	}
+	if r.State != StatePaused {
+		return errors.New("game must be paused first")
+	}
+	r.clearVote()
+	if err := r.record(GameEvent{Type: EventAdjourned, PlayerID: playerID}); err != nil {
+		return err
+	}
+	// hot-seat seats have no token, the host's link brings them all back
+	r.ResumeTokens = make(map[string]string)
+	for _, p := range r.Players {
+		if p.ResumeHash != "" {
+			r.ResumeTokens[p.ResumeHash] = p.ID
+		}
+	}
+	return nil
+}
+
+var ErrResumeLink = errors.New("resume link is not valid")
+
+// ResumeWith brings an adjourned game back (paused, until everyone agrees to
+// continue) and returns the player the token belongs to. Reports whether the
+// game came back, it does only for the first link used; the others bring
+// their players back while the game waits for the vote.
+func (r *Room) ResumeWith(token string) (string, bool, error) {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	if r.State != StateAdjourned && r.State != StatePaused {
+		return "", false, ErrResumeLink
+	}
+	playerID, ok := r.ResumeTokens[hashToken(token)]
+	if !ok {
+		return "", false, ErrResumeLink
+	}
+	if r.State != StateAdjourned {
+		return playerID, false, nil
+	}
+	if err := r.record(GameEvent{Type: EventPaused, PlayerID: playerID}); err != nil {
+		return "", false, err
+	}
+	return playerID, true, nil
+}
+
+// ResumeToken returns the resume link token of the holder of the secret
+// token, empty when the game has no link for them
+func (r *Room) ResumeToken(token string) string {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	resume := resumeToken(token)
+	if _, ok := r.ResumeTokens[hashToken(resume)]; !ok {
+		return ""
+	}
+	return resume
+}
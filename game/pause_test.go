@@ -0,0 +1,114 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+// a vote left over from the last game must not count in the next one
+func TestStartClearsVote(t *testing.T) {
+	r, players := newGame(t)
+	if _, err := r.Vote(players[0].ID, StatePaused); err != nil {
+		t.Fatal(err)
+	}
+
+	r.State = StateFinished
+	if err := r.Rematch(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Votes != nil || r.VoteFor != "" {
+		t.Errorf("rematch kept the vote %v for %q", r.Votes, r.VoteFor)
+	}
+
+	r.Votes, r.VoteFor = map[string]bool{players[0].ID: true}, StatePaused
+	for _, p := range players {
+		if err := r.SetReady(p.ID, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Start(r.HostID); err != nil {
+		t.Fatal(err)
+	}
+	if moved, err := r.Vote(players[1].ID, StatePaused); err != nil || moved {
+		t.Errorf("one vote paused the new game: %v, %v", moved, err)
+	}
+}
+
+func TestResumeWith(t *testing.T) {
+	r, players := newGame(t)
+	tokens := []string{NewToken(), NewToken()}
+	for i, p := range players {
+		if err := r.Rebind(p.ID, tokens[i]); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Vote(p.ID, StatePaused); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if link := r.ResumeToken(tokens[0]); link != "" {
+		t.Fatalf("resume link %q before adjourning", link)
+	}
+	if err := r.Adjourn(players[0].ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// the room keeps no token a link could be made of
+	snapshot, err := r.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := make([]string, len(players))
+	for i := range players {
+		links[i] = r.ResumeToken(tokens[i])
+		if links[i] == "" || strings.Contains(string(snapshot), links[i]) || strings.Contains(string(snapshot), tokens[i]) {
+			t.Fatalf("link %d is %q, the snapshot shows it or the token", i, links[i])
+		}
+	}
+
+	for i, p := range players {
+		playerID, back, err := r.ResumeWith(links[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if playerID != p.ID {
+			t.Errorf("link of %s resumed %s", p.ID, playerID)
+		}
+		if back != (i == 0) {
+			t.Errorf("link %d: back = %v, only the first link brings the game back", i, back)
+		}
+		if r.State != StatePaused {
+			t.Errorf("state after link %d = %s, want %s", i, r.State, StatePaused)
+		}
+	}
+	if _, _, err := r.ResumeWith("guess"); err == nil {
+		t.Error("unknown resume token works")
+	}
+
+	// a seat moved to another device takes its link along
+	moved := NewToken()
+	if err := r.Rebind(players[1].ID, moved); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := r.ResumeWith(links[1]); err == nil {
+		t.Error("the link of the old device still works")
+	}
+	if playerID, _, err := r.ResumeWith(r.ResumeToken(moved)); err != nil || playerID != players[1].ID {
+		t.Errorf("link of the new device = %q, %v", playerID, err)
+	}
+
+	// once the game plays again the links are over
+	for _, p := range players {
+		if _, err := r.Vote(p.ID, StatePlaying); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r.State != StatePlaying {
+		t.Fatalf("state = %s, want %s", r.State, StatePlaying)
+	}
+	if _, _, err := r.ResumeWith(links[0]); err == nil {
+		t.Error("resume link works in a game that plays again")
+	}
+	if len(r.ResumeTokens) != 0 {
+		t.Errorf("resume tokens kept: %v", r.ResumeTokens)
+	}
+}
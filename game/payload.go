@@ -0,0 +1,109 @@
+package game
+
+import (
+	"slices"
+	"yamb/broadcaster"
+)
+
+// builders of the broadcaster event payloads
+
+func (r *Room) players() []broadcaster.PlayerPayload {
+	players := make([]broadcaster.PlayerPayload, len(r.Players))
+	for i, p := range r.Players {
+		players[i] = broadcaster.PlayerPayload{
+			ID:       p.ID,
+			Username: p.Username,
+			Ready:    p.Ready,
+			Total:    p.ScoreCard.TotalScore(),
+		}
+	}
+	return players
+}
+
+func (r *Room) LobbyPayload() broadcaster.LobbyPayload {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return broadcaster.LobbyPayload{
+		State:     string(r.State),
+		HostID:    r.HostID,
+		Mode:      r.Mode,
+		Dice:      r.NumOfDice,
+		Rules:     r.Rules,
+		TurnOrder: r.TurnOrder,
+		Players:   r.players(),
+	}
+}
+
+func (r *Room) DicePayload() broadcaster.DicePayload {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return broadcaster.DicePayload{
+		PlayerID:  r.currentPlayerID(),
+		Values:    slices.Clone(r.Dice.Values),
+		Held:      slices.Clone(r.Dice.Held),
+		RollsLeft: r.Dice.RollsLeft,
+	}
+}
+
+func (r *Room) CellPayload(playerID string) broadcaster.CellPayload {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	payload := broadcaster.CellPayload{PlayerID: playerID}
+	if idx := r.playerIndex(playerID); idx != -1 {
+		sc := &r.Players[idx].ScoreCard
+		payload.Row, payload.Col = sc.GetSelectedCell()
+		payload.Announced = sc.IsAnnounced()
+	}
+	return payload
+}
+
+// TurnPayload describes the last written score
+func (r *Room) TurnPayload() broadcaster.TurnPayload {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	payload := broadcaster.TurnPayload{}
+	for i := len(r.Log) - 1; i >= 0; i-- {
+		ev := r.Log[i]
+		if ev.Type != EventWritten {
+			continue
+		}
+		payload.PlayerID, payload.Row, payload.Col, payload.Score = ev.PlayerID, ev.Row, ev.Col, ev.Score
+		if idx := r.playerIndex(ev.PlayerID); idx != -1 {
+			payload.Total = r.Players[idx].ScoreCard.TotalScore()
+		}
+		break
+	}
+	if r.State == StatePlaying {
+		payload.NextPlayerID = r.currentPlayerID()
+	}
+	return payload
+}
+
+func (r *Room) ScoresPayload() broadcaster.ScoresPayload {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return broadcaster.ScoresPayload{
+		CurrentPlayerID: r.currentPlayerID(),
+		Players:         r.players(),
+	}
+}
+
+func (r *Room) VotePayload() broadcaster.VotePayload {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	payload := broadcaster.VotePayload{State: string(r.State), VoteFor: string(r.VoteFor)}
+	for _, p := range r.Players {
+		if r.Votes[p.ID] {
+			payload.Votes = append(payload.Votes, p.ID)
+		}
+	}
+	return payload
+}
+
+// caller must hold the lock
+func (r *Room) currentPlayerID() string {
+	if r.CurrentTurn >= len(r.Players) {
+		return ""
+	}
+	return r.Players[r.CurrentTurn].ID
+}
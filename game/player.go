@@ -15,13 +15,17 @@ type Player struct {
 	ScoreCard  ScoreCard
 	Team       Team
 	FinalScore int
+	Ready      bool   // ready to start (lobby only)
+	AccountID  string // empty for anonymous players
+	TokenHash  string // of the secret token the player acts with, empty for hot-seat seats
+	ResumeHash string // of the resume link token that goes with the secret token
 }
 
 func NewPlayer(id, username string) *Player {
 	return &Player{
 		ID:         id,
 		Username:   username,
-		ScoreCard:  NewScoreCard(),
+		ScoreCard:  NewScoreCard(RulesClassic),
 		FinalScore: 0,
 	}
 }
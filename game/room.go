@@ -1,15 +1,13 @@
 package game
 
 import (
-	"log"
-	"math/rand"
+	"errors"
 	"slices"
-	"strconv"
 	"sync"
 	"time"
 	"yamb/broadcaster"
 
-	"golang.org/x/net/websocket"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type ChatMessage struct {
@@ -27,85 +25,147 @@ func NewChatMessage(playerID, message string) *ChatMessage {
 }
 
 type Room struct {
-	Mu          sync.Mutex
-	Broadcaster *broadcaster.Broadcaster
+	Mu          sync.Mutex               `json:"-"`
+	Broadcaster *broadcaster.Broadcaster `json:"-"`
 
 	ID           string
+	Version      int    // number of the last saved snapshot, see NextSnapshot
+	HostID       string // player who created the room and moderates it
+	HostToken    string // hash of the host's token, set before they join
 	Players      []*Player
 	Dice         *Dice
 	CurrentTurn  int // index of the player whose turn it is
-	GameStarted  bool
+	TurnsPlayed  int
+	TurnOrder    string
+	State        State
+	Mode         string
 	NumOfPlayers int // 2-4
 	NumOfDice    int // 5 or 6
+	Rules        string
+	Kicked       map[string]bool // token hashes of kicked players, they cannot rejoin
+
+	HotSeat  bool // the host plays every seat on one device
+	HandOver bool // hot-seat, waiting for the player on turn to take the device
+
+	RollOff       []RollOffRound // rounds of the roll-off deciding who starts
+	LastStandings []string       // player ids of the previous game, best first
+	Log           []GameEvent    // every action of every game played in the room, append-only
+
+	// pending vote to pause or resume, playerID -> agreed
+	Votes   map[string]bool
+	VoteFor State
+	// hash of a resume link token -> playerID, from the game being adjourned
+	// until it plays again
+	ResumeTokens map[string]string
+
+	// bcrypt hash of the passphrase, empty for public rooms
+	PassphraseHash []byte
 
-	ChatConns   map[*websocket.Conn]bool
 	ChatHistory []*ChatMessage
 }
 
-func NewRoom(mode, dice string) *Room {
-	numOfDice, _ := strconv.Atoi(dice)
-	numOfPlayers := 2
-	switch mode {
-	case "1v1":
-		numOfPlayers = 2
-	case "1v1v1":
-		numOfPlayers = 3
-	case "2v2":
-		numOfPlayers = 4
+func NewRoom(mode, dice, rules string) *Room {
+	numOfPlayers, numOfDice, rules, err := parseSettings(mode, dice, rules)
+	if err != nil {
+		// fall back to the defaults from the index page
+		mode, numOfPlayers, numOfDice, rules = Mode1v1, 2, 6, RulesClassic
 	}
 	return &Room{
 		Broadcaster: broadcaster.NewBroadcaster(),
 
 		Players:      []*Player{},
 		CurrentTurn:  0,
-		GameStarted:  false,
+		TurnOrder:    TurnOrderJoin,
+		State:        StateWaiting,
 		Dice:         NewDice(numOfDice),
+		Mode:         mode,
 		NumOfPlayers: numOfPlayers,
 		NumOfDice:    numOfDice,
+		Rules:        rules,
+		Kicked:       make(map[string]bool),
 
-		ChatConns:   make(map[*websocket.Conn]bool),
 		ChatHistory: []*ChatMessage{},
 	}
 }
 
-func (r *Room) IsFull() bool {
+var ErrPassphraseTooLong = errors.New("passphrase is longer than 72 bytes")
+
+// SetPassphrase makes the room private, an empty passphrase makes it public again
+func (r *Room) SetPassphrase(passphrase string) error {
+	var hash []byte
+	if passphrase != "" {
+		var err error
+		hash, err = bcrypt.GenerateFromPassword([]byte(passphrase), bcrypt.DefaultCost)
+		if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+			return ErrPassphraseTooLong
+		}
+		if err != nil {
+			return err
+		}
+	}
 	r.Mu.Lock()
 	defer r.Mu.Unlock()
-	return len(r.Players) == r.NumOfPlayers
+	r.PassphraseHash = hash
+	return nil
 }
 
-func (r *Room) AddPlayer(player *Player) error {
+func (r *Room) IsPrivate() bool {
 	r.Mu.Lock()
 	defer r.Mu.Unlock()
-	r.Players = append(r.Players, player)
-	r.Players[len(r.Players)-1].Team = Team(len(r.Players) - 1)
-	return nil
+	return len(r.PassphraseHash) > 0
 }
 
-// TODO: move to dice.go
-func (r *Room) RollDice() {
+// CheckPassphrase reports whether the passphrase matches, public rooms accept anything
+func (r *Room) CheckPassphrase(passphrase string) bool {
+	// bcrypt is slow on purpose, the room stays unlocked meanwhile
 	r.Mu.Lock()
-	defer r.Mu.Unlock()
-	if r.Dice.RollsLeft > 0 {
-		for i := range r.NumOfDice {
-			if !r.Dice.Held[i] {
-				r.Dice.Values[i] = 1 + (rand.Intn(6))
-			}
-		}
-		r.Dice.RollsLeft--
+	hash := r.PassphraseHash
+	r.Mu.Unlock()
+	if len(hash) == 0 {
+		return true
 	}
+	return bcrypt.CompareHashAndPassword(hash, []byte(passphrase)) == nil
 }
 
-func (r *Room) EndTurn() {
+func (r *Room) IsFull() bool {
 	r.Mu.Lock()
 	defer r.Mu.Unlock()
-	r.CurrentTurn = (r.CurrentTurn + 1) % len(r.Players)
-	r.Dice = NewDice(r.NumOfDice)
+	return len(r.Players) == r.NumOfPlayers
+}
+
+func (r *Room) AddPlayer(player *Player) error {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	if r.State != StateWaiting {
+		return ErrNotInLobby
+	}
+	if len(r.Players) == r.NumOfPlayers {
+		return errors.New("room full")
+	}
+	if player.TokenHash != "" && r.Kicked[player.TokenHash] {
+		return errors.New("kicked from this room")
+	}
+	if r.Mode == ModeSolo && r.HostID != "" && player.ID != r.HostID {
+		return ErrSolo
+	}
+	player.ScoreCard = NewScoreCard(r.Rules)
+	r.Players = append(r.Players, player)
+	r.Players[len(r.Players)-1].Team = Team(len(r.Players) - 1)
+	if r.Mode == ModeSolo {
+		player.Ready = true
+	}
+	r.updateReadiness()
+	return r.startSolo()
 }
 
 func (r *Room) GameEnded() bool {
 	r.Mu.Lock()
 	defer r.Mu.Unlock()
+	return r.allComplete()
+}
+
+// caller must hold the lock
+func (r *Room) allComplete() bool {
 	for _, p := range r.Players {
 		if !p.ScoreCard.IsComplete() {
 			return false
@@ -125,6 +185,13 @@ func (r *Room) GetPlayerByID(playerID string) *Player {
 	return nil
 }
 
+// CurrentPlayerID returns the id of the player on turn
+func (r *Room) CurrentPlayerID() string {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return r.currentPlayerID()
+}
+
 // used after game ends to sort players by score in order to announce winner
 func (r *Room) SortPlayersByScore() {
 	if !r.GameEnded() {
@@ -141,25 +208,3 @@ func (r *Room) SortPlayersByScore() {
 	})
 	r.Players = sorted
 }
-
-func (r *Room) RemoveConn(ws *websocket.Conn) {
-	log.Printf("Removing connection: %v\n", ws.RemoteAddr())
-
-	r.Mu.Lock()
-	delete(r.ChatConns, ws)
-	r.Mu.Unlock()
-}
-
-func (r *Room) Broadcast(msg string) {
-	r.Mu.Lock()
-	defer r.Mu.Unlock()
-
-	for ws := range r.ChatConns {
-		go func(ws *websocket.Conn) {
-			if err := websocket.Message.Send(ws, msg); err != nil {
-				log.Println("Error broadcasting:", err)
-				r.RemoveConn(ws)
-			}
-		}(ws)
-	}
-}
@@ -0,0 +1,71 @@
+package game
+
+import (
+	"strings"
+	"testing"
+)
+
+// newGame returns a started 1v1 room
+func newGame(t *testing.T) (*Room, []*Player) {
+	t.Helper()
+	hostToken, guestToken := NewToken(), NewToken()
+	r := NewRoom(Mode1v1, "6", RulesClassic)
+	r.SetHost(hostToken)
+	players := []*Player{r.NewPlayer(hostToken, "host"), r.NewPlayer(guestToken, "guest")}
+	for _, p := range players {
+		if err := r.AddPlayer(p); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.SetReady(p.ID, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Start(r.HostID); err != nil {
+		t.Fatal(err)
+	}
+	return r, players
+}
+
+func TestPassphrase(t *testing.T) {
+	r := NewRoom(Mode1v1, "6", RulesClassic)
+	if !r.CheckPassphrase("anything") {
+		t.Error("public room rejects a passphrase")
+	}
+	if err := r.SetPassphrase("secret"); err != nil {
+		t.Fatal(err)
+	}
+	if !r.IsPrivate() || !r.CheckPassphrase("secret") || r.CheckPassphrase("Secret") {
+		t.Error("private room does not check the passphrase")
+	}
+	if err := r.SetPassphrase(strings.Repeat("x", 73)); err != ErrPassphraseTooLong {
+		t.Errorf("SetPassphrase of 73 bytes = %v, want %v", err, ErrPassphraseTooLong)
+	}
+	if err := r.SetPassphrase(""); err != nil || r.IsPrivate() {
+		t.Error("empty passphrase does not make the room public")
+	}
+}
+
+func TestUpdateSettings(t *testing.T) {
+	r := NewRoom(Mode1v1, "6", RulesClassic)
+
+	// a bad turn order must not leave the other settings changed
+	if err := r.UpdateSettings(Mode1v1v1, "5", RulesExtended, "fastest"); err != ErrUnknownTurnOrder {
+		t.Fatalf("UpdateSettings with a bad turn order = %v, want %v", err, ErrUnknownTurnOrder)
+	}
+	if r.Mode != Mode1v1 || r.NumOfDice != 6 || r.Rules != RulesClassic || r.TurnOrder != TurnOrderJoin {
+		t.Errorf("settings changed to %s, %d dice, %s, %s", r.Mode, r.NumOfDice, r.Rules, r.TurnOrder)
+	}
+	if err := r.UpdateSettings("3v3", "6", RulesClassic, TurnOrderRandom); err == nil {
+		t.Error("UpdateSettings accepts an unknown mode")
+	}
+	if r.TurnOrder != TurnOrderJoin {
+		t.Errorf("turn order changed to %s with a bad mode", r.TurnOrder)
+	}
+
+	if err := r.UpdateSettings(Mode1v1v1, "5", RulesExtended, TurnOrderRandom); err != nil {
+		t.Fatal(err)
+	}
+	if r.Mode != Mode1v1v1 || r.NumOfDice != 5 || r.Rules != RulesExtended || r.TurnOrder != TurnOrderRandom {
+		t.Errorf("settings are %s, %d dice, %s, %s", r.Mode, r.NumOfDice, r.Rules, r.TurnOrder)
+	}
+}
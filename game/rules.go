@@ -0,0 +1,72 @@
+package game
+
+import (
+	"errors"
+	"strconv"
+)
+
+// game modes
+
+const (
+	Mode1v1   string = "1v1"
+	Mode1v1v1 string = "1v1v1"
+	Mode2v2   string = "2v2"
+	ModeSolo  string = "solo" // practice alone, starts as soon as the player joins
+)
+
+// rulesets (decide which columns are on the scorecard)
+
+const (
+	RulesClassic  string = "classic"  // ↓ ↑ ↑↓ A
+	RulesExtended string = "extended" // classic + middle columns
+)
+
+func Rulesets() []string {
+	return []string{RulesClassic, RulesExtended}
+}
+
+func rulesetColumns(rules string) []Column {
+	cols := []Column{
+		{ID: TopToBottom, Name: "↓"},
+		{ID: BottomToTop, Name: "↑"},
+		{ID: Free, Name: "↑↓"},
+		{ID: Announced, Name: "A"},
+	}
+	if rules == RulesExtended {
+		cols = append(cols,
+			Column{ID: MiddleToTopAndToBottom, Name: "↑M↓"},
+			Column{ID: TopAndBottomToMiddle, Name: "↓M↑"},
+		)
+	}
+	return cols
+}
+
+func numOfPlayersForMode(mode string) (int, error) {
+	switch mode {
+	case Mode1v1:
+		return 2, nil
+	case Mode1v1v1:
+		return 3, nil
+	case Mode2v2:
+		return 4, nil
+	case ModeSolo:
+		return 1, nil
+	}
+	return 0, errors.New("unknown game mode")
+}
+
+// parseSettings validates the room settings coming from forms
+func parseSettings(mode, dice, rules string) (int, int, string, error) {
+	numOfPlayers, err := numOfPlayersForMode(mode)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	numOfDice, err := strconv.Atoi(dice)
+	if err != nil || (numOfDice != 5 && numOfDice != 6) {
+		return 0, 0, "", errors.New("dice count must be 5 or 6")
+	}
+	if rules != RulesClassic && rules != RulesExtended {
+		return 0, 0, "", errors.New("unknown ruleset")
+	}
+	return numOfPlayers, numOfDice, rules, nil
+}
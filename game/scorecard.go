@@ -1,8 +1,6 @@
 package game
 
-import (
-	"errors"
-)
+import "errors"
 
 // column ids
 
@@ -59,13 +57,8 @@ type ScoreCard struct {
 	Announced    bool // whether the player has announced their move
 }
 
-func NewScoreCard() ScoreCard {
-	cols := []Column{
-		{ID: TopToBottom, Name: "↓"},
-		{ID: BottomToTop, Name: "↑"},
-		{ID: Free, Name: "↑↓"},
-		{ID: Announced, Name: "A"},
-	}
+func NewScoreCard(rules string) ScoreCard {
+	cols := rulesetColumns(rules)
 
 	rows := []Row{
 		{ID: Ones, Name: "1"},
@@ -222,6 +215,10 @@ func (sc *ScoreCard) FillCell(rowID, colID string, dice *Dice) (int, error) {
 		return score, sc.fillFree(rowID, score)
 	case Announced:
 		return score, sc.fillAnnounce(rowID, score)
+	case MiddleToTopAndToBottom:
+		return score, sc.fillMiddleToTopAndBottom(rowID, score)
+	case TopAndBottomToMiddle:
+		return score, sc.fillTopAndBottomToMiddle(rowID, score)
 	}
 
 	return 0, errors.New("unknown column ID")
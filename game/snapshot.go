@@ -0,0 +1,76 @@
+package game
+
+import (
+	"encoding/json"
+	"yamb/broadcaster"
+)
+
+// Snapshot encodes the room as JSON (e.g. to keep adjourned games on disk)
+func (r *Room) Snapshot() ([]byte, error) {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return json.Marshal(r)
+}
+
+// NextSnapshot encodes the room as the next version of its snapshot and returns
+// that version, the room keeps its version until SetVersion records the save.
+// Stores shared by several instances refuse a version that does not follow
+// the saved one, so a change made elsewhere in the meantime is not lost.
+func (r *Room) NextSnapshot() ([]byte, int, error) {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	r.Version++
+	data, err := json.Marshal(r)
+	r.Version--
+	return data, r.Version + 1, err
+}
+
+func (r *Room) SetVersion(version int) {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	r.Version = version
+}
+
+// RestoreRoom decodes a snapshot, subscriptions are not part of it
+func RestoreRoom(data []byte) (*Room, error) {
+	r := &Room{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, err
+	}
+	r.Broadcaster = broadcaster.NewBroadcaster()
+	if r.Kicked == nil {
+		r.Kicked = make(map[string]bool)
+	}
+	return r, nil
+}
+
+// Refresh replaces the state of the room with a copy restored from a snapshot
+// another server instance saved, the connections of the room stay
+func (r *Room) Refresh(saved *Room) {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	r.Version = saved.Version
+	r.HostID = saved.HostID
+	r.HostToken = saved.HostToken
+	r.Players = saved.Players
+	r.Dice = saved.Dice
+	r.CurrentTurn = saved.CurrentTurn
+	r.TurnsPlayed = saved.TurnsPlayed
+	r.TurnOrder = saved.TurnOrder
+	r.State = saved.State
+	r.Mode = saved.Mode
+	r.NumOfPlayers = saved.NumOfPlayers
+	r.NumOfDice = saved.NumOfDice
+	r.Rules = saved.Rules
+	r.Kicked = saved.Kicked
+	r.HotSeat = saved.HotSeat
+	r.HandOver = saved.HandOver
+	r.RollOff = saved.RollOff
+	r.LastStandings = saved.LastStandings
+	r.Log = saved.Log
+	r.Votes = saved.Votes
+	r.VoteFor = saved.VoteFor
+	r.ResumeTokens = saved.ResumeTokens
+	r.PassphraseHash = saved.PassphraseHash
+	r.ChatHistory = saved.ChatHistory
+}
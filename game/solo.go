@@ -0,0 +1,23 @@
+package game
+
+import "errors"
+
+// solo rooms: one player practising alone, there is no lobby to wait in
+
+var ErrSolo = errors.New("solo rooms are played by whoever created them")
+
+func (r *Room) IsSolo() bool {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return r.Mode == ModeSolo
+}
+
+// startSolo starts a solo room as soon as its player is ready, which is
+// right after joining or a rematch, caller must hold the lock
+func (r *Room) startSolo() error {
+	if r.Mode != ModeSolo || r.State != StateReady {
+		return nil
+	}
+	r.applyTurnOrder()
+	return r.record(r.startedEvent(r.Players[0].ID))
+}
@@ -0,0 +1,129 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+type State string
+
+const (
+	StateWaiting   State = "waiting"   // seats are open or not everyone is ready
+	StateReady     State = "ready"     // every seat is taken and every player is ready, host can start
+	StatePlaying   State = "playing"   // game in progress
+	StatePaused    State = "paused"    // every player agreed to pause
+	StateAdjourned State = "adjourned" // paused game stopped, resumed later with resume links
+	StateFinished  State = "finished"  // every scorecard is complete
+	StateAbandoned State = "abandoned" // cancelled by the host
+)
+
+// allowed transitions between room states
+var transitions = map[State][]State{
+	StateWaiting:   {StateReady, StateAbandoned},
+	StateReady:     {StateWaiting, StatePlaying, StateAbandoned},
+	StatePlaying:   {StatePaused, StateFinished, StateAbandoned},
+	StatePaused:    {StatePlaying, StateAdjourned, StateAbandoned},
+	StateAdjourned: {StatePaused, StateAbandoned},
+	StateFinished:  {StateWaiting}, // rematch
+	StateAbandoned: {},
+}
+
+var (
+	ErrNotInRoom   = errors.New("player not in room")
+	ErrNotYourTurn = errors.New("not your turn")
+	ErrNotPlaying  = errors.New("game is not in progress")
+	ErrNotInLobby  = errors.New("game already started")
+)
+
+func (s State) CanTransitionTo(next State) bool {
+	return slices.Contains(transitions[s], next)
+}
+
+func (r *Room) GetState() State {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return r.State
+}
+
+// InLobby reports whether the game has not started yet
+func (r *Room) InLobby() bool {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return r.inLobby()
+}
+
+// caller must hold the lock
+func (r *Room) inLobby() bool {
+	return r.State == StateWaiting || r.State == StateReady
+}
+
+// caller must hold the lock
+func (r *Room) transition(next State) error {
+	if r.State == next {
+		return nil
+	}
+	if !r.State.CanTransitionTo(next) {
+		return fmt.Errorf("cannot go from %s to %s", r.State, next)
+	}
+	r.State = next
+	// resume links only bring back an adjourned game, once it plays (or is
+	// over) they are gone
+	if next != StateAdjourned && next != StatePaused {
+		r.ResumeTokens = nil
+	}
+	return nil
+}
+
+// moves between waiting and ready depending on the seats and ready flags,
+// caller must hold the lock
+func (r *Room) updateReadiness() {
+	if !r.inLobby() {
+		return
+	}
+	allReady := len(r.Players) == r.NumOfPlayers
+	for _, p := range r.Players {
+		allReady = allReady && p.Ready
+	}
+	if allReady {
+		r.transition(StateReady)
+	} else {
+		r.transition(StateWaiting)
+	}
+}
+
+// SetReady marks the player as (not) ready to start
+func (r *Room) SetReady(playerID string, ready bool) error {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	if !r.inLobby() {
+		return ErrNotInLobby
+	}
+	idx := r.playerIndex(playerID)
+	if idx == -1 {
+		return ErrNotInRoom
+	}
+	r.Players[idx].Ready = ready
+	r.updateReadiness()
+	return nil
+}
+
+// Start is called by the host once every player is ready
+func (r *Room) Start(playerID string) error {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	if playerID != r.HostID {
+		return errors.New("only the host can start the game")
+	}
+	if r.State != StateReady {
+		return errors.New("not every player is ready")
+	}
+	r.applyTurnOrder()
+	if err := r.record(r.startedEvent(playerID)); err != nil {
+		return err
+	}
+	r.clearVote()
+	// whoever starts may not be the one holding the device
+	r.HandOver = r.HotSeat
+	return nil
+}
@@ -0,0 +1,131 @@
+package game
+
+import (
+	crand "crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+)
+
+// players prove who they are with a secret token (the player_id cookie of a
+// browser or the token the JSON API hands out), everything the others see
+// (payloads, logs, records) uses the random id of the player in the room.
+// Rooms keep only hashes of the tokens.
+
+// NewToken returns a random secret token
+func NewToken() string {
+	return randomHex(32)
+}
+
+// newPlayerID returns a random public player id
+func newPlayerID() string {
+	return randomHex(16)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	crand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// resumeToken is the resume link token of the holder of the secret token,
+// rooms keep only its hash so they can tell the link apart but not make it
+func resumeToken(token string) string {
+	return hashToken("resume:" + token)
+}
+
+func sameHash(hash, token string) bool {
+	return hash != "" && token != "" && subtle.ConstantTimeCompare([]byte(hash), []byte(hashToken(token))) == 1
+}
+
+// SetHost makes whoever holds the token host of the room, the id they get
+// when joining is known before they do
+func (r *Room) SetHost(token string) {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	r.HostID = newPlayerID()
+	r.HostToken = hashToken(token)
+}
+
+// PlayerFor returns the id of the player the token belongs to, the host's id
+// also before they joined, empty for anyone else
+func (r *Room) PlayerFor(token string) string {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return r.playerFor(token)
+}
+
+// caller must hold the lock
+func (r *Room) playerFor(token string) string {
+	for _, p := range r.Players {
+		if sameHash(p.TokenHash, token) {
+			return p.ID
+		}
+	}
+	if sameHash(r.HostToken, token) {
+		return r.HostID
+	}
+	return ""
+}
+
+// NewPlayer returns a player for the holder of the token, with the id set
+// aside for the host if that is them
+func (r *Room) NewPlayer(token, username string) *Player {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	id := newPlayerID()
+	if sameHash(r.HostToken, token) {
+		id = r.HostID
+	}
+	p := NewPlayer(id, username)
+	p.TokenHash = hashToken(token)
+	p.ResumeHash = hashToken(resumeToken(token))
+	return p
+}
+
+// NewSeat returns a player without a token of their own, for hot-seat rooms
+func NewSeat(username string) *Player {
+	return NewPlayer(newPlayerID(), username)
+}
+
+// AccountPlayer returns the id of the player of the account, empty when the
+// account has not joined
+func (r *Room) AccountPlayer(accountID string) string {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	for _, p := range r.Players {
+		if accountID != "" && p.AccountID == accountID {
+			return p.ID
+		}
+	}
+	return ""
+}
+
+// Rebind hands the player's seat to the holder of the token, the old token
+// stops working (e.g. a resume link opened on another device)
+func (r *Room) Rebind(playerID, token string) error {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	idx := r.playerIndex(playerID)
+	if idx == -1 {
+		return ErrNotInRoom
+	}
+	p := r.Players[idx]
+	p.TokenHash = hashToken(token)
+	if playerID == r.HostID {
+		r.HostToken = p.TokenHash
+	}
+	// the link of the old token stops working too, the new one replaces it
+	oldResume := p.ResumeHash
+	p.ResumeHash = hashToken(resumeToken(token))
+	if _, ok := r.ResumeTokens[oldResume]; ok {
+		delete(r.ResumeTokens, oldResume)
+		r.ResumeTokens[p.ResumeHash] = playerID
+	}
+	return nil
+}
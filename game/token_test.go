@@ -0,0 +1,94 @@
+package game
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// everything spectators see must show public ids, never tokens or their hashes
+func TestPublicDataHasNoTokens(t *testing.T) {
+	hostToken, guestToken := NewToken(), NewToken()
+
+	r := NewRoom(Mode1v1, "6", RulesClassic)
+	r.ID = "123456"
+	r.SetHost(hostToken)
+	host := r.NewPlayer(hostToken, "host")
+	guest := r.NewPlayer(guestToken, "guest")
+	for _, p := range []*Player{host, guest} {
+		if err := r.AddPlayer(p); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.SetReady(p.ID, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Start(host.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.RollDice(r.CurrentPlayerID()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Vote(guest.ID, StatePaused); err != nil {
+		t.Fatal(err)
+	}
+	r.ChatHistory = append(r.ChatHistory, NewChatMessage(guest.ID, "hi"))
+
+	if host.ID != r.HostID {
+		t.Errorf("host joined as %q, want the reserved id %q", host.ID, r.HostID)
+	}
+	if got := r.PlayerFor(guestToken); got != guest.ID {
+		t.Errorf("PlayerFor(guest token) = %q, want %q", got, guest.ID)
+	}
+	if got := r.PlayerFor(guest.ID); got != "" {
+		t.Errorf("the public id works as a token, got player %q", got)
+	}
+
+	public := map[string]any{
+		"lobby":  r.LobbyPayload(),
+		"dice":   r.DicePayload(),
+		"cell":   r.CellPayload(host.ID),
+		"turn":   r.TurnPayload(),
+		"scores": r.ScoresPayload(),
+		"vote":   r.VotePayload(),
+		"api":    r.APIRoom(),
+		"chat":   r.APIChat(),
+		"log":    r.Events(),
+	}
+	secrets := []string{hostToken, guestToken, hashToken(hostToken), hashToken(guestToken)}
+	for name, v := range public {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, secret := range secrets {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("%s shows a token: %s", name, data)
+			}
+		}
+	}
+}
+
+func TestRebind(t *testing.T) {
+	oldToken, newToken := NewToken(), NewToken()
+
+	r := NewRoom(Mode1v1, "6", RulesClassic)
+	r.SetHost(oldToken)
+	host := r.NewPlayer(oldToken, "host")
+	if err := r.AddPlayer(host); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Rebind(host.ID, newToken); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.PlayerFor(newToken); got != host.ID {
+		t.Errorf("PlayerFor(new token) = %q, want %q", got, host.ID)
+	}
+	if got := r.PlayerFor(oldToken); got != "" {
+		t.Errorf("the old token still works, got player %q", got)
+	}
+	if err := r.Rebind("nobody", newToken); err != ErrNotInRoom {
+		t.Errorf("Rebind of a stranger = %v, want %v", err, ErrNotInRoom)
+	}
+}
@@ -0,0 +1,111 @@
+package game
+
+import (
+	"errors"
+	"slices"
+)
+
+// actions of the player whose turn it is
+
+// caller must hold the lock
+func (r *Room) checkTurn(playerID string) (*Player, error) {
+	if r.State == StatePaused {
+		return nil, ErrPaused
+	}
+	if r.State != StatePlaying {
+		return nil, ErrNotPlaying
+	}
+	idx := r.playerIndex(playerID)
+	if idx == -1 {
+		return nil, ErrNotInRoom
+	}
+	if idx != r.CurrentTurn {
+		return nil, ErrNotYourTurn
+	}
+	return r.Players[idx], nil
+}
+
+func (r *Room) RollDice(playerID string) error {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return r.record(GameEvent{
+		Type:     EventRolled,
+		PlayerID: playerID,
+		Dice:     r.Dice.next(),
+		Held:     slices.Clone(r.Dice.Held),
+	})
+}
+
+func (r *Room) ToggleDie(playerID string, index int) error {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return r.record(GameEvent{Type: EventToggled, PlayerID: playerID, Die: index})
+}
+
+// SetHeld keeps or releases a die, a die that already is as wanted stays
+// untouched. It reports whether the die changed.
+func (r *Room) SetHeld(playerID string, index int, held bool) (bool, error) {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	if _, err := r.checkTurn(playerID); err != nil {
+		return false, err
+	}
+	if index < 0 || index >= len(r.Dice.Held) {
+		return false, errors.New("no such die")
+	}
+	if r.Dice.Held[index] == held {
+		return false, nil
+	}
+	return true, r.record(GameEvent{Type: EventToggled, PlayerID: playerID, Die: index})
+}
+
+func (r *Room) SelectCell(playerID, rowID, colID string) error {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return r.record(GameEvent{Type: EventSelected, PlayerID: playerID, Row: rowID, Col: colID})
+}
+
+func (r *Room) Announce(playerID string) error {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	return r.record(GameEvent{Type: EventAnnounced, PlayerID: playerID})
+}
+
+// WriteScore fills the selected cell with the current dice and ends the turn,
+// the game finishes once every scorecard is complete
+func (r *Room) WriteScore(playerID string) (int, error) {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	player, err := r.checkTurn(playerID)
+	if err != nil {
+		return 0, err
+	}
+
+	row, col := player.ScoreCard.GetSelectedCell()
+	score, err := player.ScoreCard.CalculateScore(row, r.Dice)
+	if err != nil {
+		return 0, err
+	}
+	err = r.record(GameEvent{
+		Type:     EventWritten,
+		PlayerID: playerID,
+		Dice:     slices.Clone(r.Dice.Values),
+		Held:     slices.Clone(r.Dice.Held),
+		Row:      row,
+		Col:      col,
+		Score:    score,
+	})
+	if err != nil {
+		return 0, err
+	}
+	// the device goes to the next player
+	r.HandOver = r.HotSeat && r.State == StatePlaying
+	return score, nil
+}
+
+// caller must hold the lock
+func (r *Room) endTurn() {
+	r.CurrentTurn = (r.CurrentTurn + 1) % len(r.Players)
+	r.TurnsPlayed++
+	r.Dice = NewDice(r.NumOfDice)
+}
@@ -0,0 +1,40 @@
+package game
+
+import "testing"
+
+func TestSetHeld(t *testing.T) {
+	r, players := newGame(t)
+	current := r.CurrentPlayerID()
+	if err := r.RollDice(current); err != nil {
+		t.Fatal(err)
+	}
+	other := players[0].ID
+	if other == current {
+		other = players[1].ID
+	}
+
+	tests := []struct {
+		name        string
+		playerID    string
+		die         int
+		held        bool
+		wantChanged bool
+		wantErr     bool
+	}{
+		{"keep", current, 0, true, true, false},
+		{"keep again", current, 0, true, false, false},
+		{"release", current, 0, false, true, false},
+		{"release again", current, 0, false, false, false},
+		{"no such die", current, 6, true, false, true},
+		{"not on turn", other, 1, true, false, true},
+	}
+	for _, tt := range tests {
+		changed, err := r.SetHeld(tt.playerID, tt.die, tt.held)
+		if changed != tt.wantChanged || (err != nil) != tt.wantErr {
+			t.Errorf("%s: SetHeld = %v, %v, want %v, error %v", tt.name, changed, err, tt.wantChanged, tt.wantErr)
+		}
+	}
+	if r.Dice.Held[1] {
+		t.Error("a player kept a die off turn")
+	}
+}
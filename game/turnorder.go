@@ -0,0 +1,140 @@
+package game
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+)
+
+// turn order policies (who starts the game)
+
+const (
+	TurnOrderJoin    string = "join"    // order of the lobby seats
+	TurnOrderRandom  string = "random"  // shuffled at start
+	TurnOrderRollOff string = "rolloff" // everyone rolls, highest sum starts
+	TurnOrderLoser   string = "loser"   // loser of the last game starts (rematches)
+)
+
+func TurnOrders() []string {
+	return []string{TurnOrderJoin, TurnOrderRandom, TurnOrderRollOff, TurnOrderLoser}
+}
+
+var ErrUnknownTurnOrder = errors.New("unknown turn order")
+
+// one round of the roll-off, playerID -> rolled dice
+type RollOffRound map[string][]int
+
+func (r *Room) SetTurnOrder(policy string) error {
+	if !slices.Contains(TurnOrders(), policy) {
+		return ErrUnknownTurnOrder
+	}
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	if !r.inLobby() {
+		return ErrNotInLobby
+	}
+	r.TurnOrder = policy
+	return nil
+}
+
+// applyTurnOrder reorders the players (turn order) when the game starts,
+// caller must hold the lock
+func (r *Room) applyTurnOrder() {
+	r.RollOff = nil
+	switch r.TurnOrder {
+	case TurnOrderRandom:
+		rand.Shuffle(len(r.Players), func(i, j int) {
+			r.Players[i], r.Players[j] = r.Players[j], r.Players[i]
+		})
+	case TurnOrderRollOff:
+		r.startWith(r.rollOff())
+	case TurnOrderLoser:
+		// loser is the last player of the previous standings that is still in the room
+		for i := len(r.LastStandings) - 1; i >= 0; i-- {
+			if idx := r.playerIndex(r.LastStandings[i]); idx != -1 {
+				r.startWith(idx)
+				break
+			}
+		}
+	}
+}
+
+// rollOff rolls for every player until one has the highest sum, returns their index
+func (r *Room) rollOff() int {
+	contenders := make([]int, len(r.Players))
+	for i := range r.Players {
+		contenders[i] = i
+	}
+	for len(contenders) > 1 {
+		round := RollOffRound{}
+		best := -1
+		winners := []int{}
+		for _, idx := range contenders {
+			dice := NewDice(r.NumOfDice)
+			dice.Roll()
+			round[r.Players[idx].ID] = dice.Values
+
+			sum := 0
+			for _, v := range dice.Values {
+				sum += v
+			}
+			if sum > best {
+				best = sum
+				winners = []int{idx}
+			} else if sum == best {
+				winners = append(winners, idx)
+			}
+		}
+		r.RollOff = append(r.RollOff, round)
+		// tied players roll again
+		contenders = winners
+	}
+	return contenders[0]
+}
+
+// rotates the players so that the player at idx starts, the rest keep their order
+func (r *Room) startWith(idx int) {
+	r.Players = slices.Concat(r.Players[idx:], r.Players[:idx])
+}
+
+// Rematch moves a finished game back to the lobby with the same players
+func (r *Room) Rematch() error {
+	r.Mu.Lock()
+	defer r.Mu.Unlock()
+	if r.inLobby() {
+		// someone else already started the rematch
+		return nil
+	}
+	if r.State != StateFinished {
+		return errors.New("game is not finished")
+	}
+
+	standings := make([]*Player, len(r.Players))
+	copy(standings, r.Players)
+	slices.SortStableFunc(standings, func(a, b *Player) int {
+		return b.ScoreCard.TotalScore() - a.ScoreCard.TotalScore()
+	})
+	r.LastStandings = make([]string, len(standings))
+	for i, p := range standings {
+		r.LastStandings[i] = p.ID
+	}
+
+	// teams follow the lobby seats, so they give back the original order
+	slices.SortFunc(r.Players, func(a, b *Player) int {
+		return int(a.Team) - int(b.Team)
+	})
+	for _, p := range r.Players {
+		p.ScoreCard = NewScoreCard(r.Rules)
+	}
+	r.unready()
+	r.Dice = NewDice(r.NumOfDice)
+	r.CurrentTurn = 0
+	r.TurnsPlayed = 0
+	r.RollOff = nil
+	r.clearVote()
+	if err := r.transition(StateWaiting); err != nil {
+		return err
+	}
+	r.updateReadiness()
+	return r.startSolo()
+}
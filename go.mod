@@ -8,5 +8,9 @@ require (
 	github.com/a-h/templ v0.3.960
 	github.com/go-chi/chi/v5 v5.2.3
 	github.com/google/uuid v1.6.0
+	go.etcd.io/bbolt v1.4.3
+	golang.org/x/crypto v0.40.0
 	golang.org/x/net v0.42.0
 )
+
+require golang.org/x/sys v0.34.0 // indirect
@@ -3,30 +3,44 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
-	"math/rand"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
+	"yamb/accounts"
+	"yamb/api"
 	"yamb/broadcaster"
 	"yamb/game"
 	"yamb/views"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/google/uuid"
 	"golang.org/x/net/websocket"
 )
 
 var (
 	rooms   = make(map[string]*game.Room)
 	roomsMu sync.Mutex
+
+	// throttles wrong passphrase attempts per IP
+	passphraseThrottle = NewThrottle(5, 10*time.Minute)
 )
 
 func IndexHandler(w http.ResponseWriter, r *http.Request) {
 	lang := getLang(r)
 
-	err := views.Index(lang).Render(r.Context(), w)
+	username := ""
+	if acc := currentAccount(r); acc != nil {
+		username = acc.Username
+	}
+
+	err := views.Index(lang, username).Render(r.Context(), w)
 	if err != nil {
 		HxError(w, "could not render index", http.StatusInternalServerError)
 		log.Println("error rendering index:", err)
@@ -41,17 +55,31 @@ func CreateRoomHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	roomID := fmt.Sprintf("%06d", rand.Intn(1000000))
 	mode := r.FormValue("mode")
 	dice := r.FormValue("dice")
+	rules := r.FormValue("rules")
 
-	roomsMu.Lock()
-	rooms[roomID] = game.NewRoom(mode, dice)
-	roomsMu.Unlock()
+	room := game.NewRoom(mode, dice, rules)
+	if err := room.SetPassphrase(r.FormValue("passphrase")); err != nil {
+		HxError(w, fmt.Sprintf("bad passphrase: %v", err), http.StatusBadRequest)
+		return
+	}
+	room.HotSeat = r.FormValue("hot_seat") == "on" && !room.IsSolo()
+	if err := room.SetTurnOrder(r.FormValue("turn_order")); err != nil {
+		log.Println("keeping default turn order:", err)
+	}
+	// whoever creates the room hosts it
+	room.SetHost(ensurePlayerToken(w, r))
+
+	if err := addRoom(room); err != nil {
+		HxError(w, "could not create room", http.StatusInternalServerError)
+		log.Println("error adding room:", err)
+		return
+	}
 
 	lang := getLang(r)
 
-	err := views.RoomLink(roomID, lang).Render(r.Context(), w)
+	err := views.RoomLink(room.ID, lang).Render(r.Context(), w)
 	if err != nil {
 		HxError(w, "could not render room link", http.StatusInternalServerError)
 		log.Println("error rendering room link:", err)
@@ -61,9 +89,7 @@ func CreateRoomHandler(w http.ResponseWriter, r *http.Request) {
 
 func RoomLinkHandler(w http.ResponseWriter, r *http.Request) {
 	roomID := chi.URLParam(r, "roomID")
-	roomsMu.Lock()
-	_, ok := rooms[roomID]
-	roomsMu.Unlock()
+	room, ok := findRoom(roomID)
 	if !ok {
 		HxError(w, "room does not exist", 404)
 		return
@@ -71,7 +97,12 @@ func RoomLinkHandler(w http.ResponseWriter, r *http.Request) {
 
 	lang := getLang(r)
 
-	err := views.UsernameEntry(roomID, lang).Render(r.Context(), w)
+	username := ""
+	if acc := currentAccount(r); acc != nil {
+		username = acc.Username
+	}
+
+	err := views.UsernameEntry(roomID, lang, room.IsPrivate(), username).Render(r.Context(), w)
 	if err != nil {
 		HxError(w, "could not render username entry", http.StatusInternalServerError)
 		log.Println("error rendering username entry:", err)
@@ -83,25 +114,55 @@ func JoinRoomHandler(w http.ResponseWriter, r *http.Request) {
 	roomID := r.FormValue("room_id")
 	username := r.FormValue("username")
 
-	roomsMu.Lock()
-	room, ok := rooms[roomID]
-	roomsMu.Unlock()
+	room, ok := findRoom(roomID)
 	if !ok {
 		HxError(w, "room does not exist", 404)
 		return
 	}
 
+	if room.IsPrivate() {
+		ip := clientIP(r)
+		if !passphraseThrottle.Allowed(ip) {
+			HxError(w, "too many wrong passphrases, try again later", http.StatusTooManyRequests)
+			return
+		}
+		if !room.CheckPassphrase(r.FormValue("passphrase")) {
+			passphraseThrottle.Fail(ip)
+			HxError(w, "wrong passphrase", http.StatusForbidden)
+			log.Printf("wrong passphrase for room %s from %s\n", roomID, ip)
+			return
+		}
+		passphraseThrottle.Reset(ip)
+	}
+
+	token := ensurePlayerToken(w, r)
+	playerID := room.PlayerFor(token)
+	acc := currentAccount(r)
+	if playerID == "" && acc != nil {
+		playerID = rebindAccount(room, acc, token)
+	}
+	if room.GetPlayerByID(playerID) != nil {
+		// already joined, just go back to the game
+		http.Redirect(w, r, fmt.Sprintf("/room/%s", roomID), http.StatusSeeOther)
+		return
+	}
+
+	if !room.InLobby() {
+		HxError(w, "game already started", http.StatusForbidden)
+		return
+	}
+
+	// the host adds the other seats of a hot-seat room
+	if room.HotSeat && !room.IsHost(playerID) {
+		HxError(w, "this room is played on one device", http.StatusForbidden)
+		return
+	}
+
 	if room.IsFull() {
 		HxError(w, "room full", http.StatusForbidden)
 		return
 	}
 
-	playerID := uuid.New().String()
-	http.SetCookie(w, &http.Cookie{
-		Name:  "player_id",
-		Value: playerID,
-		Path:  "/",
-	})
 	// TODO: do we want to use this cookie instead of passing room_id in forms?
 	http.SetCookie(w, &http.Cookie{
 		Name:  "room_id",
@@ -109,18 +170,26 @@ func JoinRoomHandler(w http.ResponseWriter, r *http.Request) {
 		Path:  "/",
 	})
 
-	err := room.AddPlayer(game.NewPlayer(playerID, username))
+	player := room.NewPlayer(token, username)
+	if acc != nil {
+		player.AccountID = acc.ID
+	}
+	err := room.AddPlayer(player)
 	if err != nil {
-		HxError(w, fmt.Sprintf("could not add player: %v", err), http.StatusInternalServerError)
+		HxError(w, fmt.Sprintf("could not add player: %v", err), http.StatusForbidden)
 		log.Println("error adding player to room:", err)
 		return
 	}
 
-	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.PlayerJoined})
-	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.ScoreUpdated})
-
-	if len(room.Players) == room.NumOfPlayers {
-		room.GameStarted = true
+	if err := saveRoom(room); err != nil {
+		HxError(w, err.Error(), errorStatus(err))
+		return
+	}
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.PlayerJoined, Data: room.LobbyPayload()})
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.ScoreUpdated, Data: room.ScoresPayload()})
+	// solo rooms start as soon as their player joins
+	if room.GetState() == game.StatePlaying {
+		room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.GameStarted, Data: room.LobbyPayload()})
 	}
 
 	http.Redirect(w, r, fmt.Sprintf("/room/%s", roomID), http.StatusSeeOther)
@@ -128,9 +197,7 @@ func JoinRoomHandler(w http.ResponseWriter, r *http.Request) {
 
 func RoomPageHandler(w http.ResponseWriter, r *http.Request) {
 	roomID := chi.URLParam(r, "roomID")
-	roomsMu.Lock()
-	room, ok := rooms[roomID]
-	roomsMu.Unlock()
+	room, ok := findRoom(roomID)
 	if !ok {
 		HxError(w, "room does not exist", 404)
 		return
@@ -138,15 +205,20 @@ func RoomPageHandler(w http.ResponseWriter, r *http.Request) {
 
 	playerCookie, err := r.Cookie("player_id")
 	if err != nil {
-		http.Redirect(w, r, fmt.Sprintf("/room/%s", roomID), http.StatusSeeOther)
+		http.Redirect(w, r, fmt.Sprintf("/%s", roomID), http.StatusSeeOther)
 		log.Println("no player cookie:", err)
 		return
 	}
-	playerID := playerCookie.Value
+	playerID := room.PlayerFor(playerCookie.Value)
+
+	if !canAccessRoom(r, room) || room.GetPlayerByID(playerID) == nil {
+		http.Redirect(w, r, fmt.Sprintf("/%s", roomID), http.StatusSeeOther)
+		return
+	}
 
 	lang := getLang(r)
 
-	err = views.RoomPage(roomID, playerID, lang, room).Render(r.Context(), w)
+	err = views.RoomPage(roomID, room.Seat(playerID), lang, room).Render(r.Context(), w)
 	if err != nil {
 		HxError(w, "could not render room page", http.StatusInternalServerError)
 		log.Println("error rendering room page:", err)
@@ -156,25 +228,36 @@ func RoomPageHandler(w http.ResponseWriter, r *http.Request) {
 
 func ResultsPageHandler(w http.ResponseWriter, r *http.Request) {
 	roomID := chi.URLParam(r, "roomID")
-	roomsMu.Lock()
-	room, ok := rooms[roomID]
-	roomsMu.Unlock()
+	room, ok := findRoom(roomID)
 	if !ok {
 		HxError(w, "room does not exist", 404)
 		return
 	}
 
+	if !canAccessRoom(r, room) {
+		HxError(w, "private room", http.StatusForbidden)
+		return
+	}
+
 	playCookie, err := r.Cookie("player_id")
 	if err != nil {
 		HxError(w, "no player cookie", http.StatusForbidden)
 		log.Println("no player cookie:", err)
 		return
 	}
-	playerID := playCookie.Value
+	playerID := room.PlayerFor(playCookie.Value)
 
 	lang := getLang(r)
 
-	err = views.ResultsPage(roomID, playerID, lang, room).Render(r.Context(), w)
+	var solo *accounts.SoloResult
+	if room.IsSolo() {
+		solo, err = accountStore.SoloResult(soloOwner(room, playerID), roomID)
+		if err != nil {
+			log.Println("error loading solo result:", err)
+		}
+	}
+
+	err = views.ResultsPage(roomID, playerID, lang, room, solo).Render(r.Context(), w)
 	if err != nil {
 		HxError(w, "could not render results page", http.StatusInternalServerError)
 		log.Println("error rendering results page:", err)
@@ -182,115 +265,100 @@ func ResultsPageHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func RollDiceHandler(w http.ResponseWriter, r *http.Request) {
-	roomID := r.FormValue("room_id")
-	roomsMu.Lock()
-	room, ok := rooms[roomID]
-	roomsMu.Unlock()
+// GameLogHandler returns every recorded action of the room as JSON (e.g. to settle disputes),
+// players appear with their public ids only
+func GameLogHandler(w http.ResponseWriter, r *http.Request) {
+	roomID := chi.URLParam(r, "roomID")
+	room, ok := findRoom(roomID)
 	if !ok {
 		HxError(w, "room does not exist", 404)
 		return
 	}
 
-	room.RollDice()
-	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.DiceAreaUpdated})
-	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.ScoreUpdated})
-
-	playerCookie, err := r.Cookie("player_id")
-	if err != nil {
-		HxError(w, "no player cookie", http.StatusForbidden)
-		log.Println("no player cookie:", err)
+	if !canAccessRoom(r, room) {
+		HxError(w, "private room", http.StatusForbidden)
 		return
 	}
-	playerID := playerCookie.Value
 
-	lang := getLang(r)
-
-	err = views.DiceArea(roomID, playerID, lang, room).Render(r.Context(), w)
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(room.Events())
 	if err != nil {
-		HxError(w, "could not render dice area", http.StatusInternalServerError)
-		log.Println("error rendering dice area:", err)
+		log.Println("error encoding game log:", err)
 		return
 	}
 }
 
-func ToggleDiceHandler(w http.ResponseWriter, r *http.Request) {
-	roomID := r.FormValue("room_id")
-	roomsMu.Lock()
-	room, ok := rooms[roomID]
-	roomsMu.Unlock()
+// hxAction runs the action for the player of the cookie in the room of the
+// form, like the JSON API does, and answers errors itself. body is the request
+// of the action, nil for none.
+func hxAction(w http.ResponseWriter, r *http.Request, action apiAction, body any) (*game.Room, string, bool) {
+	room, ok := findRoom(r.FormValue("room_id"))
 	if !ok {
 		HxError(w, "room does not exist", 404)
-		return
+		return nil, "", false
 	}
 
-	dieIdx, _ := strconv.Atoi(r.FormValue("die_index"))
-	room.Dice.ToggleDie(dieIdx)
-	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.DiceAreaUpdated})
-	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.ScoreUpdated})
-
-	playCookie, err := r.Cookie("player_id")
+	playerCookie, err := r.Cookie("player_id")
 	if err != nil {
 		HxError(w, "no player cookie", http.StatusForbidden)
 		log.Println("no player cookie:", err)
-		return
+		return nil, "", false
 	}
-	playerID := playCookie.Value
-
-	lang := getLang(r)
+	// in hot-seat rooms the host acts for the player on turn
+	playerID := room.Seat(room.PlayerFor(playerCookie.Value))
 
-	err = views.DiceArea(roomID, playerID, lang, room).Render(r.Context(), w)
-	if err != nil {
-		HxError(w, "could not render dice area", http.StatusInternalServerError)
-		log.Println("error rendering dice area:", err)
-		return
+	var data []byte
+	if body != nil {
+		if data, err = json.Marshal(body); err != nil {
+			HxError(w, "bad request", http.StatusBadRequest)
+			return nil, "", false
+		}
 	}
+	if _, err := action(room, playerID, data); err != nil {
+		HxError(w, err.Error(), errorStatus(err))
+		log.Printf("error in room %s: %v\n", room.ID, err)
+		return nil, "", false
+	}
+	return room, playerID, true
 }
 
-func SelectCellHandler(w http.ResponseWriter, r *http.Request) {
-	roomID := r.FormValue("room_id")
-	roomsMu.Lock()
-	room, ok := rooms[roomID]
-	roomsMu.Unlock()
+func RollDiceHandler(w http.ResponseWriter, r *http.Request) {
+	room, playerID, ok := hxAction(w, r, rollAction, nil)
 	if !ok {
-		HxError(w, "room does not exist", 404)
 		return
 	}
 
-	playerCookie, err := r.Cookie("player_id")
+	err := views.DiceArea(room.ID, playerID, getLang(r), room).Render(r.Context(), w)
 	if err != nil {
-		HxError(w, "no player cookie", http.StatusForbidden)
-		log.Println("no player cookie:", err)
+		HxError(w, "could not render dice area", http.StatusInternalServerError)
+		log.Println("error rendering dice area:", err)
 		return
 	}
-	playerID := playerCookie.Value
-
-	lang := getLang(r)
+}
 
-	player := room.GetPlayerByID(playerID)
-	if player == nil {
-		HxError(w, "player not in room", http.StatusForbidden)
+func ToggleDiceHandler(w http.ResponseWriter, r *http.Request) {
+	dieIdx, _ := strconv.Atoi(r.FormValue("die_index"))
+	room, playerID, ok := hxAction(w, r, toggleAction, api.HoldRequest{Die: dieIdx})
+	if !ok {
 		return
 	}
 
-	if room.Players[room.CurrentTurn].ID != playerID {
-		HxError(w, "not your turn", http.StatusForbidden)
+	err := views.DiceArea(room.ID, playerID, getLang(r), room).Render(r.Context(), w)
+	if err != nil {
+		HxError(w, "could not render dice area", http.StatusInternalServerError)
+		log.Println("error rendering dice area:", err)
 		return
 	}
+}
 
-	row := r.FormValue("row")
-	col := r.FormValue("col")
-
-	err = player.ScoreCard.SelectCell(row, col)
-	if err != nil {
-		HxError(w, fmt.Sprintf("could not select cell: %v", err), http.StatusBadRequest)
-		log.Println("error selecting cell:", err)
+func SelectCellHandler(w http.ResponseWriter, r *http.Request) {
+	req := api.SelectRequest{Row: r.FormValue("row"), Col: r.FormValue("col")}
+	room, playerID, ok := hxAction(w, r, selectAction, req)
+	if !ok {
 		return
 	}
 
-	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.CellSelected})
-
-	err = views.MainScoreCard(roomID, playerID, lang, room).Render(r.Context(), w)
+	err := views.MainScoreCard(room.ID, playerID, getLang(r), room).Render(r.Context(), w)
 	if err != nil {
 		HxError(w, "could not render score", http.StatusInternalServerError)
 		log.Println("error rendering score:", err)
@@ -298,97 +366,59 @@ func SelectCellHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// WriteScoreHandler announces the selected cell or writes to it, the turn
+// ends when the player writes
 func WriteScoreHandler(w http.ResponseWriter, r *http.Request) {
-	roomID := r.FormValue("room_id")
-	roomsMu.Lock()
-	room, ok := rooms[roomID]
-	roomsMu.Unlock()
+	action := writeAction
+	if r.FormValue("announce") == "true" {
+		action = announceAction
+	}
+	room, playerID, ok := hxAction(w, r, action, nil)
 	if !ok {
-		HxError(w, "room does not exist", 404)
 		return
 	}
 
-	playerCookie, err := r.Cookie("player_id")
-	if err != nil {
-		HxError(w, "no player cookie", http.StatusForbidden)
-		log.Println("no player cookie:", err)
-		return
+	// the next player of a hot-seat room takes the device first
+	if room.HandOverPending() {
+		w.Header().Set("HX-Refresh", "true")
 	}
-	playerID := playerCookie.Value
-
-	lang := getLang(r)
 
-	player := room.GetPlayerByID(playerID)
-	if player == nil {
-		HxError(w, "player not in room", http.StatusForbidden)
+	err := views.MainScoreCard(room.ID, playerID, getLang(r), room).Render(r.Context(), w)
+	if err != nil {
+		HxError(w, "could not render score", http.StatusInternalServerError)
+		log.Println("error rendering score:", err)
 		return
 	}
+}
 
-	if room.Players[room.CurrentTurn].ID != playerID {
-		HxError(w, "not your turn", http.StatusForbidden)
+// notifyTurn tells the player on turn, and only them, that it is their turn
+func notifyTurn(room *game.Room) {
+	if room.GetState() != game.StatePlaying {
 		return
 	}
-
-	announce := r.FormValue("announce") == "true"
-
-	if announce {
-		player.ScoreCard.Announce()
-		room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.ScoreAnnounced})
-		err = views.MainScoreCard(roomID, playerID, lang, room).Render(r.Context(), w)
-		if err != nil {
-			HxError(w, "could not render score", http.StatusInternalServerError)
-			log.Println("error rendering score:", err)
-			return
-		}
-	} else {
-		row, col := player.ScoreCard.GetSelectedCell()
-
-		_, err = player.ScoreCard.FillCell(row, col, room.Dice)
-		if err != nil {
-			HxError(w, fmt.Sprintf("could not fill cell: %v", err), http.StatusBadRequest)
-			log.Println("error filling cell:", err)
-			return
-		}
-
-		player.ScoreCard.CalculateSums()
-
-		room.EndTurn() // end the turn when the user enters result in a cell
-		player.ScoreCard.UnselectCell()
-		room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.TurnEnded})
-		room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.ScoreUpdated})
-
-		err = views.MainScoreCard(roomID, playerID, lang, room).Render(r.Context(), w)
-		if err != nil {
-			HxError(w, "could not render score", http.StatusInternalServerError)
-			log.Println("error rendering score:", err)
-			return
-		}
-
-		if room.GameEnded() {
-			room.SortPlayersByScore()
-			room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.GameEnded})
-			return
-		}
-	}
+	room.Broadcaster.Send(room.CurrentPlayerID(), broadcaster.Event{Name: broadcaster.YourTurn})
 }
 
 func OtherScorecardsHandler(w http.ResponseWriter, r *http.Request) {
 	roomID := chi.URLParam(r, "roomID")
-	roomsMu.Lock()
-	room, ok := rooms[roomID]
-	roomsMu.Unlock()
+	room, ok := findRoom(roomID)
 	if !ok {
 		HxError(w, "room does not exist", 404)
 		return
 	}
 
+	if !canAccessRoom(r, room) {
+		HxError(w, "private room", http.StatusForbidden)
+		return
+	}
+
 	playerCookie, err := r.Cookie("player_id")
 	if err != nil {
 		HxError(w, "no player cookie", http.StatusForbidden)
 		log.Println("no player cookie:", err)
 		return
 	}
-	playerID := playerCookie.Value
+	playerID := room.Seat(room.PlayerFor(playerCookie.Value))
 
 	lang := getLang(r)
 
@@ -402,46 +432,154 @@ func OtherScorecardsHandler(w http.ResponseWriter, r *http.Request) {
 func EventsHandler(w http.ResponseWriter, r *http.Request) {
 	roomID := chi.URLParam(r, "roomID")
 
-	roomsMu.Lock()
-	room, ok := rooms[roomID]
-	roomsMu.Unlock()
+	room, ok := findRoom(roomID)
 	if !ok {
 		HxError(w, "room does not exist", 404)
 		return
 	}
 
+	if !canAccessRoom(r, room) {
+		HxError(w, "private room", http.StatusForbidden)
+		return
+	}
+
 	// SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 
-	flusher, ok := w.(http.Flusher)
-	if !ok {
+	if _, ok := w.(http.Flusher); !ok {
 		HxError(w, "Streaming unsupported", http.StatusInternalServerError)
 		return
 	}
 
-	ch := room.Broadcaster.Subscribe()
+	// spectators of public rooms have no player and only get the room-wide events
+	playerID := ""
+	if playerCookie, err := r.Cookie("player_id"); err == nil && room.GetPlayerByID(room.PlayerFor(playerCookie.Value)) != nil {
+		playerID = room.PlayerFor(playerCookie.Value)
+	}
+	lang := getLang(r)
+
+	ch, missed, err := room.Broadcaster.Subscribe(playerID, r.Header.Get("Last-Event-ID"),
+		broadcaster.TopicGame, broadcaster.TopicLobby, broadcaster.TopicPresence)
 	defer room.Broadcaster.Unsubscribe(ch)
 
 	ctx := r.Context()
+	rc := http.NewResponseController(w)
+	config := room.Broadcaster.Config
+
+	// every write gets a deadline so that a stuck client does not keep the handler around
+	deadline := func() error {
+		err := rc.SetWriteDeadline(time.Now().Add(config.WriteTimeout))
+		if errors.Is(err, http.ErrNotSupported) {
+			return nil
+		}
+		return err
+	}
+	send := func(ev broadcaster.Event) error {
+		if err := deadline(); err != nil {
+			return err
+		}
+		data, err := ev.Payload()
+		if err != nil {
+			log.Println("error encoding event payload:", err)
+			data = "_"
+		}
+		writeEvent(w, room.Broadcaster.EventID(ev), ev.Name, data)
+
+		// push the player's own dice area instead of letting the client fetch it
+		if playerID != "" && slices.Contains(diceAreaEvents, ev.Name) && room.GetPlayerByID(playerID) != nil {
+			var buf bytes.Buffer
+			err := views.DiceArea(roomID, room.Seat(playerID), lang, room).Render(ctx, &buf)
+			if err != nil {
+				log.Println("error rendering dice area:", err)
+			} else {
+				writeEvent(w, "", broadcaster.DiceAreaHTML, buf.String())
+			}
+		}
+		return rc.Flush()
+	}
+
+	// catch up after a reconnect
+	if errors.Is(err, broadcaster.ErrResync) {
+		if err := deadline(); err != nil {
+			return
+		}
+		writeEvent(w, "", broadcaster.Resync, "_")
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+	for _, ev := range missed {
+		if err := send(ev); err != nil {
+			return
+		}
+	}
+
+	// comments keep proxies from closing the idle connection
+	heartbeat := time.NewTicker(config.Heartbeat)
+	defer heartbeat.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case ev := <-ch:
-			fmt.Fprintf(w, "event: %s\n", ev.Name)
-			fmt.Fprintf(w, "data: _\n\n")
-			flusher.Flush()
+		case <-heartbeat.C:
+			if err := deadline(); err != nil {
+				return
+			}
+			fmt.Fprint(w, ": heartbeat\n\n")
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case ev, ok := <-ch:
+			if !ok {
+				// evicted for staying behind, the browser reconnects with Last-Event-ID
+				return
+			}
+			if err := send(ev); err != nil {
+				return
+			}
+			// events were dropped while this client was slow, it gets them
+			// from the history when it reconnects
+			if len(ch) == 0 && room.Broadcaster.Behind(ch) {
+				return
+			}
 		}
 	}
 }
 
+// events after which every player gets their dice area again
+var diceAreaEvents = []broadcaster.EventName{
+	broadcaster.TurnEnded,
+	broadcaster.PlayerJoined,
+	broadcaster.PlayerKicked,
+	broadcaster.TurnOrderChanged,
+	broadcaster.HostTransferred,
+	broadcaster.PlayerReady,
+	broadcaster.GameStarted,
+	broadcaster.PauseVoted,
+	broadcaster.GamePaused,
+	broadcaster.GameResumed,
+	broadcaster.GameAdjourned,
+}
+
+// writeEvent writes one SSE message, every line of the data gets its own data field,
+// messages without an id leave the client's last event id as it was
+func writeEvent(w io.Writer, id string, name broadcaster.EventName, data string) {
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\n", name)
+	for line := range strings.Lines(data) {
+		fmt.Fprintf(w, "data: %s\n", strings.TrimSuffix(line, "\n"))
+	}
+	fmt.Fprint(w, "\n")
+}
+
 func DiceAreaHandler(w http.ResponseWriter, r *http.Request) {
 	roomID := chi.URLParam(r, "roomID")
-	roomsMu.Lock()
-	room, ok := rooms[roomID]
-	roomsMu.Unlock()
+	room, ok := findRoom(roomID)
 	if !ok {
 		HxError(w, "room does not exist", 404)
 		return
@@ -453,7 +591,13 @@ func DiceAreaHandler(w http.ResponseWriter, r *http.Request) {
 		log.Println("no player cookie:", err)
 		return
 	}
-	playerID := playerCookie.Value
+	playerID := room.Seat(room.PlayerFor(playerCookie.Value))
+
+	if room.GetPlayerByID(playerID) == nil {
+		// kicked by the host
+		w.Header().Set("HX-Redirect", "/")
+		return
+	}
 
 	lang := getLang(r)
 
@@ -467,14 +611,17 @@ func DiceAreaHandler(w http.ResponseWriter, r *http.Request) {
 
 func PlayerCounterHandler(w http.ResponseWriter, r *http.Request) {
 	roomID := chi.URLParam(r, "roomID")
-	roomsMu.Lock()
-	room, ok := rooms[roomID]
-	roomsMu.Unlock()
+	room, ok := findRoom(roomID)
 	if !ok {
 		HxError(w, "room does not exist", 404)
 		return
 	}
 
+	if !canAccessRoom(r, room) {
+		HxError(w, "private room", http.StatusForbidden)
+		return
+	}
+
 	lang := getLang(r)
 
 	err := views.PlayerCounter(lang, room).Render(r.Context(), w)
@@ -487,21 +634,24 @@ func PlayerCounterHandler(w http.ResponseWriter, r *http.Request) {
 
 func CellSelectedHandler(w http.ResponseWriter, r *http.Request) {
 	roomID := chi.URLParam(r, "roomID")
-	roomsMu.Lock()
-	room, ok := rooms[roomID]
-	roomsMu.Unlock()
+	room, ok := findRoom(roomID)
 	if !ok {
 		HxError(w, "room does not exist", 404)
 		return
 	}
 
+	if !canAccessRoom(r, room) {
+		HxError(w, "private room", http.StatusForbidden)
+		return
+	}
+
 	playerCookie, err := r.Cookie("player_id")
 	if err != nil {
 		HxError(w, "no player cookie", http.StatusForbidden)
 		log.Println("no player cookie:", err)
 		return
 	}
-	playerID := playerCookie.Value
+	playerID := room.Seat(room.PlayerFor(playerCookie.Value))
 
 	lang := getLang(r)
 
@@ -540,46 +690,108 @@ func HxError(w http.ResponseWriter, msg string, status int) {
 
 func ChatWebsocketHandler(ws *websocket.Conn) {
 	roomID := chi.URLParam(ws.Request(), "roomID")
-	roomsMu.Lock()
-	room, ok := rooms[roomID]
-	roomsMu.Unlock()
-	if !ok {
+	room, ok := findRoom(roomID)
+	if !ok || !canAccessRoom(ws.Request(), room) {
 		ws.Close()
 		return
 	}
 
-	room.Mu.Lock()
-	room.ChatConns[ws] = true
-	room.Mu.Unlock()
-
+	token, playerID := "", ""
+	if playerCookie, err := ws.Request().Cookie("player_id"); err == nil && room.GetPlayerByID(room.PlayerFor(playerCookie.Value)) != nil {
+		token, playerID = playerCookie.Value, room.PlayerFor(playerCookie.Value)
+	}
+	ch, _, _ := room.Broadcaster.Subscribe(playerID, "", broadcaster.TopicChat)
 	defer func() {
-		room.RemoveConn(ws)
+		room.Broadcaster.Unsubscribe(ch)
 		ws.Close()
 	}()
 
+	// render the messages of the chat topic for this connection
+	go func() {
+		defer ws.Close()
+		for ev := range ch {
+			var msg broadcaster.ChatPayload
+			if err := ev.Decode(&msg); err != nil {
+				log.Println("error decoding chat message:", err)
+				continue
+			}
+			player := &game.Player{ID: msg.PlayerID, Username: msg.Username}
+			chatMsg := &game.ChatMessage{PlayerID: msg.PlayerID, Message: msg.Message, SentAt: msg.SentAt}
+			var buf bytes.Buffer
+			if err := views.ChatMessageWrapper(player, chatMsg).Render(context.Background(), &buf); err != nil {
+				log.Println("error rendering chat message:", err)
+				continue
+			}
+			if err := websocket.Message.Send(ws, buf.String()); err != nil {
+				return
+			}
+			// messages were dropped, the client reloads the history when it reconnects
+			if len(ch) == 0 && room.Broadcaster.Behind(ch) {
+				return
+			}
+		}
+	}()
+
 	for {
 		var msg struct {
-			Msg      string `json:"msg"`
-			PlayerID string `json:"player_id"`
+			Msg string `json:"msg"`
 		}
 		if err := websocket.JSON.Receive(ws, &msg); err != nil {
 			break
 		}
-		player := room.GetPlayerByID(msg.PlayerID)
+		// the cookie says who is chatting, spectators only read, and so
+		// does a browser whose seat moved to another device
+		player := room.GetPlayerByID(playerID)
+		if player == nil || room.PlayerFor(token) != playerID {
+			continue
+		}
 		chatMsg := game.NewChatMessage(player.ID, msg.Msg)
 		// add message to room chat history
 		room.Mu.Lock()
 		room.ChatHistory = append(room.ChatHistory, chatMsg)
 		room.Mu.Unlock()
-		// Render chat message HTML
-		var buf bytes.Buffer
-		err := views.ChatMessageWrapper(player, chatMsg).Render(context.Background(), &buf)
-		if err != nil {
-			log.Println("error rendering chat message:", err)
-			break
+		if err := saveRoom(room); err != nil {
+			log.Println("error saving chat message:", err)
+			continue
 		}
-		room.Broadcast(buf.String())
+		room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.ChatMessage, Data: broadcaster.ChatPayload{
+			PlayerID: player.ID,
+			Username: player.Username,
+			Message:  chatMsg.Message,
+			SentAt:   chatMsg.SentAt,
+		}})
+	}
+}
+
+// ensurePlayerToken returns the secret token of the browser from the
+// player_id cookie, creating the cookie if needed. Rooms map it to the public
+// id of the player (see game.Room.PlayerFor), it is never shown to others.
+func ensurePlayerToken(w http.ResponseWriter, r *http.Request) string {
+	if playerCookie, err := r.Cookie("player_id"); err == nil && playerCookie.Value != "" {
+		return playerCookie.Value
+	}
+	token := game.NewToken()
+	http.SetCookie(w, &http.Cookie{
+		Name:     "player_id",
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token
+}
+
+// canAccessRoom reports whether the request may see the room, private rooms are
+// visible only to players who joined with the passphrase
+func canAccessRoom(r *http.Request, room *game.Room) bool {
+	if !room.IsPrivate() {
+		return true
+	}
+	playerCookie, err := r.Cookie("player_id")
+	if err != nil {
+		return false
 	}
+	return room.GetPlayerByID(room.PlayerFor(playerCookie.Value)) != nil
 }
 
 func getLang(r *http.Request) string {
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"yamb/accounts"
+	"yamb/game"
+	"yamb/i18n"
+	"yamb/store"
+)
+
+// newTestServer serves the routes of main with fresh rooms, room store and
+// accounts
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	if err := i18n.LoadLocales("assets/locales"); err != nil {
+		t.Fatal(err)
+	}
+	fileStore, err := store.NewFileStore(filepath.Join(t.TempDir(), "rooms"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomStore = fileStore
+	accountStore, err = accounts.Open(filepath.Join(t.TempDir(), "accounts.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	roomsMu.Lock()
+	rooms = make(map[string]*game.Room)
+	roomsMu.Unlock()
+
+	srv := httptest.NewServer(newRouter())
+	t.Cleanup(func() {
+		srv.Close()
+		accountStore.Close()
+		roomStore, accountStore = nil, nil
+	})
+	return srv
+}
+
+// browser is a client with its own cookies, like a player's browser
+type browser struct {
+	t      *testing.T
+	srv    *httptest.Server
+	client *http.Client
+}
+
+func newBrowser(t *testing.T, srv *httptest.Server) *browser {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &browser{t: t, srv: srv, client: &http.Client{Jar: jar}}
+}
+
+func (b *browser) do(method, path string, form url.Values) (int, string) {
+	b.t.Helper()
+	req, err := http.NewRequest(method, b.srv.URL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		b.t.Fatal(err)
+	}
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		b.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		b.t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func (b *browser) post(path string, form url.Values) int {
+	b.t.Helper()
+	status, _ := b.do(http.MethodPost, path, form)
+	return status
+}
+
+// token returns the secret of the browser's player_id cookie
+func (b *browser) token() string {
+	u, _ := url.Parse(b.srv.URL)
+	for _, c := range b.client.Jar.Cookies(u) {
+		if c.Name == "player_id" {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+// startGame has the host create a 1v1 room that both browsers join and start
+func startGame(t *testing.T, host, guest *browser) *game.Room {
+	t.Helper()
+	if status := host.post("/create-room", url.Values{"mode": {game.Mode1v1}, "dice": {"6"}, "rules": {game.RulesClassic}}); status != http.StatusOK {
+		t.Fatalf("create room: status %d", status)
+	}
+	var room *game.Room
+	roomsMu.Lock()
+	for _, rm := range rooms {
+		room = rm
+	}
+	roomsMu.Unlock()
+
+	for i, b := range []*browser{host, guest} {
+		if status := b.post("/join-room", url.Values{"room_id": {room.ID}, "username": {[]string{"host", "guest"}[i]}}); status != http.StatusOK {
+			t.Fatalf("join room: status %d", status)
+		}
+		if status := b.post("/ready", url.Values{"room_id": {room.ID}, "ready": {"true"}}); status != http.StatusOK {
+			t.Fatalf("ready: status %d", status)
+		}
+	}
+	if status := host.post("/start-game", url.Values{"room_id": {room.ID}}); status != http.StatusOK {
+		t.Fatalf("start game: status %d", status)
+	}
+	return room
+}
+
+// the log settles disputes, anyone watching may read it but it must not hand
+// out what players act with
+func TestGameLogShowsNoTokens(t *testing.T) {
+	srv := newTestServer(t)
+	host, guest := newBrowser(t, srv), newBrowser(t, srv)
+	room := startGame(t, host, guest)
+
+	current := host
+	if room.CurrentPlayerID() != room.HostID {
+		current = guest
+	}
+	if status := current.post("/roll-dice", url.Values{"room_id": {room.ID}}); status != http.StatusOK {
+		t.Fatalf("roll: status %d", status)
+	}
+
+	status, body := newBrowser(t, srv).do(http.MethodGet, "/room/"+room.ID+"/log", nil)
+	if status != http.StatusOK {
+		t.Fatalf("log: status %d", status)
+	}
+	if !strings.Contains(body, room.HostID) {
+		t.Errorf("log does not name the host by id: %s", body)
+	}
+	for _, b := range []*browser{host, guest} {
+		if b.token() == "" || strings.Contains(body, b.token()) {
+			t.Errorf("log shows the token %q: %s", b.token(), body)
+		}
+	}
+}
+
+// the counters are only served on DEBUG_ADDR
+func TestDebugVarsNotPublic(t *testing.T) {
+	srv := newTestServer(t)
+	status, body := newBrowser(t, srv).do(http.MethodGet, "/debug/vars", nil)
+	if strings.Contains(body, "broadcaster") {
+		t.Errorf("/debug/vars is public: status %d", status)
+	}
+}
+
+// the resume link works on an instance that does not have the room in memory
+func TestResumeLinkLoadsRoom(t *testing.T) {
+	srv := newTestServer(t)
+	host, guest := newBrowser(t, srv), newBrowser(t, srv)
+	room := startGame(t, host, guest)
+	guestID := room.PlayerFor(guest.token())
+
+	for _, b := range []*browser{host, guest} {
+		if status := b.post("/pause", url.Values{"room_id": {room.ID}, "agree": {"true"}}); status != http.StatusOK {
+			t.Fatalf("pause: status %d", status)
+		}
+	}
+	if status := host.post("/adjourn", url.Values{"room_id": {room.ID}}); status != http.StatusOK {
+		t.Fatalf("adjourn: status %d", status)
+	}
+	// the guest's browser shows their link
+	status, body := guest.do(http.MethodGet, "/room/"+room.ID+"/resume-link", nil)
+	link := "/resume/" + room.ID + "/" + room.ResumeToken(guest.token())
+	if status != http.StatusOK || !strings.Contains(body, link) {
+		t.Fatalf("resume link: status %d, %s does not show %s", status, body, link)
+	}
+	if _, body := newBrowser(t, srv).do(http.MethodGet, "/room/"+room.ID+"/resume-link", nil); strings.Contains(body, "/resume/") {
+		t.Errorf("a stranger gets a resume link: %s", body)
+	}
+
+	roomsMu.Lock()
+	delete(rooms, room.ID)
+	roomsMu.Unlock()
+
+	// the guest comes back on another device
+	device := newBrowser(t, srv)
+	if status, _ := device.do(http.MethodGet, link, nil); status != http.StatusOK {
+		t.Fatalf("resume link: status %d", status)
+	}
+	loaded, ok := findRoom(room.ID)
+	if !ok {
+		t.Fatal("room was not loaded")
+	}
+	if got := loaded.PlayerFor(device.token()); got != guestID {
+		t.Errorf("resume link seated %q, want %q", got, guestID)
+	}
+	if loaded.GetState() != game.StatePaused {
+		t.Errorf("room is %s, want %s", loaded.GetState(), game.StatePaused)
+	}
+
+	if status, _ := device.do(http.MethodGet, "/resume/"+room.ID+"/"+strings.Repeat("0", 32), nil); status != http.StatusNotFound {
+		t.Errorf("unknown resume token: status %d", status)
+	}
+
+	// after the game plays again the link is dead
+	for _, b := range []*browser{host, device} {
+		if status := b.post("/resume", url.Values{"room_id": {room.ID}, "agree": {"true"}}); status != http.StatusOK {
+			t.Fatalf("resume: status %d", status)
+		}
+	}
+	if status, _ := newBrowser(t, srv).do(http.MethodGet, link, nil); status != http.StatusNotFound {
+		t.Errorf("resume link of a game that plays again: status %d", status)
+	}
+}
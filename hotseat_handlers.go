@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"yamb/broadcaster"
+	"yamb/game"
+)
+
+func AddSeatHandler(w http.ResponseWriter, r *http.Request) {
+	room, playerID, ok := hostRoom(w, r)
+	if !ok {
+		return
+	}
+
+	username := strings.TrimSpace(r.FormValue("username"))
+	if username == "" {
+		HxError(w, "seat needs a name", http.StatusBadRequest)
+		return
+	}
+
+	// seats have no token of their own, the host acts for them
+	err := room.AddSeat(game.NewSeat(username))
+	if err != nil {
+		HxError(w, fmt.Sprintf("could not add seat: %v", err), errorStatus(err))
+		log.Println("error adding seat:", err)
+		return
+	}
+
+	if err := saveRoom(room); err != nil {
+		HxError(w, err.Error(), errorStatus(err))
+		return
+	}
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.PlayerJoined, Data: room.LobbyPayload()})
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.ScoreUpdated, Data: room.ScoresPayload()})
+
+	renderLobby(w, r, room, playerID)
+}
+
+func HandOverHandler(w http.ResponseWriter, r *http.Request) {
+	room, _, ok := hostRoom(w, r)
+	if !ok {
+		return
+	}
+
+	room.ConfirmHandOver()
+	if err := saveRoom(room); err != nil {
+		HxError(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	w.Header().Set("HX-Refresh", "true")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"yamb/game"
+	"yamb/rating"
+	"yamb/views"
+)
+
+const leaderboardLength = 100
+
+func LeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	pool := rating.Pool{Mode: game.Mode1v1, Dice: 6}
+	if mode := r.URL.Query().Get("mode"); mode != "" {
+		pool.Mode = mode
+	}
+	if dice := r.URL.Query().Get("dice"); dice != "" {
+		n, err := strconv.Atoi(dice)
+		if err != nil {
+			HxError(w, "dice count must be a number", http.StatusBadRequest)
+			return
+		}
+		pool.Dice = n
+	}
+	if !slices.Contains(rating.Pools(), pool) {
+		HxError(w, "unknown leaderboard", http.StatusBadRequest)
+		return
+	}
+
+	entries, err := accountStore.Leaderboard(pool, leaderboardLength)
+	if err != nil {
+		HxError(w, "could not load leaderboard", http.StatusInternalServerError)
+		log.Println("error loading leaderboard:", err)
+		return
+	}
+
+	lang := getLang(r)
+
+	err = views.LeaderboardPage(lang, pool, entries).Render(r.Context(), w)
+	if err != nil {
+		HxError(w, "could not render leaderboard", http.StatusInternalServerError)
+		log.Println("error rendering leaderboard:", err)
+		return
+	}
+}
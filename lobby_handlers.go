@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"yamb/broadcaster"
+	"yamb/game"
+	"yamb/views"
+)
+
+// hostRoom returns the room from the form if the requesting player is its host
+func hostRoom(w http.ResponseWriter, r *http.Request) (*game.Room, string, bool) {
+	roomID := r.FormValue("room_id")
+	room, ok := findRoom(roomID)
+	if !ok {
+		HxError(w, "room does not exist", 404)
+		return nil, "", false
+	}
+
+	playerCookie, err := r.Cookie("player_id")
+	if err != nil {
+		HxError(w, "no player cookie", http.StatusForbidden)
+		log.Println("no player cookie:", err)
+		return nil, "", false
+	}
+	playerID := room.PlayerFor(playerCookie.Value)
+
+	if !room.IsHost(playerID) {
+		HxError(w, "only the host can do that", http.StatusForbidden)
+		return nil, "", false
+	}
+
+	return room, playerID, true
+}
+
+func renderLobby(w http.ResponseWriter, r *http.Request, room *game.Room, playerID string) {
+	roomID := r.FormValue("room_id")
+	lang := getLang(r)
+
+	err := views.DiceArea(roomID, playerID, lang, room).Render(r.Context(), w)
+	if err != nil {
+		HxError(w, "could not render dice area", http.StatusInternalServerError)
+		log.Println("error rendering dice area:", err)
+		return
+	}
+}
+
+func ReadyHandler(w http.ResponseWriter, r *http.Request) {
+	roomID := r.FormValue("room_id")
+	room, ok := findRoom(roomID)
+	if !ok {
+		HxError(w, "room does not exist", 404)
+		return
+	}
+
+	playerCookie, err := r.Cookie("player_id")
+	if err != nil {
+		HxError(w, "no player cookie", http.StatusForbidden)
+		log.Println("no player cookie:", err)
+		return
+	}
+	playerID := room.PlayerFor(playerCookie.Value)
+
+	err = room.SetReady(playerID, r.FormValue("ready") == "true")
+	if err != nil {
+		HxError(w, fmt.Sprintf("could not change ready status: %v", err), errorStatus(err))
+		log.Println("error changing ready status:", err)
+		return
+	}
+
+	if err := saveRoom(room); err != nil {
+		HxError(w, err.Error(), errorStatus(err))
+		return
+	}
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.PlayerReady, Data: room.LobbyPayload()})
+
+	renderLobby(w, r, room, playerID)
+}
+
+func StartGameHandler(w http.ResponseWriter, r *http.Request) {
+	room, playerID, ok := hostRoom(w, r)
+	if !ok {
+		return
+	}
+
+	err := room.Start(playerID)
+	if err != nil {
+		HxError(w, fmt.Sprintf("could not start game: %v", err), http.StatusConflict)
+		log.Println("error starting game:", err)
+		return
+	}
+
+	if err := saveRoom(room); err != nil {
+		HxError(w, err.Error(), errorStatus(err))
+		return
+	}
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.GameStarted, Data: room.LobbyPayload()})
+	notifyTurn(room)
+
+	renderLobby(w, r, room, playerID)
+}
+
+func PlayAgainHandler(w http.ResponseWriter, r *http.Request) {
+	roomID := r.FormValue("room_id")
+	room, ok := findRoom(roomID)
+	if !ok {
+		HxError(w, "room does not exist", 404)
+		return
+	}
+
+	playerCookie, err := r.Cookie("player_id")
+	if err != nil || room.GetPlayerByID(room.PlayerFor(playerCookie.Value)) == nil {
+		HxError(w, "player not in room", http.StatusForbidden)
+		return
+	}
+
+	err = room.Rematch()
+	if err != nil {
+		HxError(w, fmt.Sprintf("could not start rematch: %v", err), http.StatusConflict)
+		log.Println("error starting rematch:", err)
+		return
+	}
+
+	if err := saveRoom(room); err != nil {
+		HxError(w, err.Error(), errorStatus(err))
+		return
+	}
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.PlayerReady, Data: room.LobbyPayload()})
+	// a solo rematch starts right away
+	if room.GetState() == game.StatePlaying {
+		room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.GameStarted, Data: room.LobbyPayload()})
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/room/%s", roomID), http.StatusSeeOther)
+}
+
+func KickPlayerHandler(w http.ResponseWriter, r *http.Request) {
+	room, playerID, ok := hostRoom(w, r)
+	if !ok {
+		return
+	}
+
+	target := r.FormValue("target")
+	err := room.Kick(target)
+	if err != nil {
+		HxError(w, fmt.Sprintf("could not kick player: %v", err), http.StatusBadRequest)
+		log.Println("error kicking player:", err)
+		return
+	}
+
+	if err := saveRoom(room); err != nil {
+		HxError(w, err.Error(), errorStatus(err))
+		return
+	}
+	room.Broadcaster.Send(target, broadcaster.Event{Name: broadcaster.Kicked})
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.PlayerKicked, Data: room.LobbyPayload()})
+
+	renderLobby(w, r, room, playerID)
+}
+
+func MovePlayerHandler(w http.ResponseWriter, r *http.Request) {
+	room, playerID, ok := hostRoom(w, r)
+	if !ok {
+		return
+	}
+
+	delta := 1
+	if r.FormValue("direction") == "up" {
+		delta = -1
+	}
+
+	err := room.MovePlayer(r.FormValue("target"), delta)
+	if err != nil {
+		HxError(w, fmt.Sprintf("could not move player: %v", err), http.StatusBadRequest)
+		log.Println("error moving player:", err)
+		return
+	}
+
+	if err := saveRoom(room); err != nil {
+		HxError(w, err.Error(), errorStatus(err))
+		return
+	}
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.TurnOrderChanged, Data: room.LobbyPayload()})
+
+	renderLobby(w, r, room, playerID)
+}
+
+func UpdateSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	room, playerID, ok := hostRoom(w, r)
+	if !ok {
+		return
+	}
+
+	err := room.UpdateSettings(r.FormValue("mode"), r.FormValue("dice"), r.FormValue("rules"), r.FormValue("turn_order"))
+	if err != nil {
+		HxError(w, fmt.Sprintf("could not change settings: %v", err), http.StatusBadRequest)
+		log.Println("error changing settings:", err)
+		return
+	}
+
+	if err := saveRoom(room); err != nil {
+		HxError(w, err.Error(), errorStatus(err))
+		return
+	}
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.SettingsChanged, Data: room.LobbyPayload()})
+
+	renderLobby(w, r, room, playerID)
+}
+
+func TransferHostHandler(w http.ResponseWriter, r *http.Request) {
+	room, playerID, ok := hostRoom(w, r)
+	if !ok {
+		return
+	}
+
+	err := room.TransferHost(r.FormValue("target"))
+	if err != nil {
+		HxError(w, fmt.Sprintf("could not transfer host: %v", err), http.StatusBadRequest)
+		log.Println("error transferring host:", err)
+		return
+	}
+
+	if err := saveRoom(room); err != nil {
+		HxError(w, err.Error(), errorStatus(err))
+		return
+	}
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.HostTransferred, Data: room.LobbyPayload()})
+
+	renderLobby(w, r, room, playerID)
+}
+
+func CancelRoomHandler(w http.ResponseWriter, r *http.Request) {
+	room, _, ok := hostRoom(w, r)
+	if !ok {
+		return
+	}
+
+	err := room.Cancel()
+	if err != nil {
+		HxError(w, fmt.Sprintf("could not cancel room: %v", err), http.StatusConflict)
+		log.Println("error cancelling room:", err)
+		return
+	}
+	roomsMu.Lock()
+	delete(rooms, r.FormValue("room_id"))
+	roomsMu.Unlock()
+	deleteRoom(r.FormValue("room_id"))
+
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.RoomCancelled})
+
+	w.Header().Set("HX-Redirect", "/")
+}
@@ -1,21 +1,105 @@
 package main
 
 import (
+	"expvar"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"golang.org/x/net/websocket"
 
+	"yamb/accounts"
+	"yamb/api"
+	"yamb/broadcaster"
 	"yamb/i18n"
+	"yamb/resp"
+	"yamb/store"
 )
 
 func main() {
+	err := i18n.LoadLocales("assets/locales")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// rooms survive restarts
+	dataDir := os.Getenv("DATA_DIR")
+	if dataDir == "" {
+		dataDir = "data"
+	}
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		log.Fatal(err)
+	}
+	redisURL := os.Getenv("REDIS_URL")
+	if redisURL != "" {
+		// several instances share the rooms, their events and the throttles
+		// through redis
+		client, err := resp.Dial(redisURL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		roomStore = store.NewRedisStore(client)
+		eventBackend, err = broadcaster.NewRedisBackend(redisURL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		passphraseThrottle.Share(client, "passphrase")
+		loginThrottle.Share(client, "login")
+	} else {
+		roomStore, err = store.NewFileStore(filepath.Join(dataDir, "rooms"))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	err = loadRooms()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// optional player accounts
+	if redisURL != "" {
+		// with a connection of their own, their transactions hold it
+		client, err := resp.Dial(redisURL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		accountStore = accounts.OpenRedis(client)
+	} else {
+		accountStore, err = accounts.Open(filepath.Join(dataDir, "accounts.db"))
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	loginCodes = os.Getenv("LOGIN_CODES") == "true"
+	trustProxy = os.Getenv("TRUST_PROXY") == "true"
+
+	// counters of the event delivery (published, dropped, evicted), only on
+	// an address of its own (e.g. localhost:6060) that is not published
+	if debugAddr := os.Getenv("DEBUG_ADDR"); debugAddr != "" {
+		debug := http.NewServeMux()
+		debug.Handle("/debug/vars", expvar.Handler())
+		go func() {
+			log.Fatal(http.ListenAndServe(debugAddr, debug))
+		}()
+	}
+
+	r := newRouter()
+
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+	port = ":" + port
+	log.Fatal(http.ListenAndServe(port, r))
+}
+
+// newRouter registers the pages, actions and the API
+func newRouter() *chi.Mux {
 	r := chi.NewRouter()
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
@@ -31,11 +115,6 @@ func main() {
 	localeFiles := http.StripPrefix("/locales/", http.FileServer(http.Dir("assets/locales")))
 	r.Handle("/locales/*", localeFiles)
 
-	err := i18n.LoadLocales("assets/locales")
-	if err != nil {
-		log.Fatal(err)
-	}
-
 	// landing page
 	r.Get("/", IndexHandler)
 
@@ -54,6 +133,27 @@ func main() {
 	// results page
 	r.Get("/room/{roomID}/results", ResultsPageHandler)
 
+	// game log (JSON) and replay of the last game
+	r.Get("/room/{roomID}/log", GameLogHandler)
+	r.Get("/room/{roomID}/replay", ReplayPageHandler)
+	r.Get("/room/{roomID}/replay/step", ReplayStepHandler)
+
+	// game records of finished games
+	r.Get("/room/{roomID}/export.json", ExportJSONHandler)
+	r.Get("/room/{roomID}/export.csv", ExportCSVHandler)
+	r.Get("/verify", VerifyPageHandler)
+	r.Post("/verify", VerifyRecordHandler)
+
+	// accounts
+	r.Get("/login", LoginPageHandler)
+	r.Post("/login", LoginHandler)
+	r.Post("/register", RegisterHandler)
+	r.Post("/login/code", RequestCodeHandler)
+	r.Post("/login/code/verify", CodeLoginHandler)
+	r.Post("/logout", LogoutHandler)
+	r.Get("/players/{username}", ProfileHandler)
+	r.Get("/leaderboard", LeaderboardHandler)
+
 	// change language
 	r.Post("/set-lang", SetLangHandler)
 
@@ -63,6 +163,7 @@ func main() {
 	r.Get("/room/{roomID}/dice-area", DiceAreaHandler)
 	r.Get("/room/{roomID}/player-counter", PlayerCounterHandler)
 	r.Get("/room/{roomID}/cell-selected", CellSelectedHandler)
+	r.Get("/room/{roomID}/resume-link", ResumeLinkURLHandler)
 
 	// Actions (HTMX endpoints)
 	r.Post("/roll-dice", RollDiceHandler)
@@ -70,13 +171,32 @@ func main() {
 	r.Post("/select-cell", SelectCellHandler)
 	r.Post("/write-score", WriteScoreHandler)
 
+	// Lobby (HTMX endpoints)
+	r.Post("/ready", ReadyHandler)
+	r.Post("/start-game", StartGameHandler)
+	r.Post("/play-again", PlayAgainHandler)
+	r.Post("/kick-player", KickPlayerHandler)
+	r.Post("/move-player", MovePlayerHandler)
+	r.Post("/update-settings", UpdateSettingsHandler)
+	r.Post("/transfer-host", TransferHostHandler)
+	r.Post("/cancel-room", CancelRoomHandler)
+
+	// Hot-seat (HTMX endpoints)
+	r.Post("/add-seat", AddSeatHandler)
+	r.Post("/hand-over", HandOverHandler)
+
+	// Pause / adjourn (HTMX endpoints)
+	r.Post("/pause", PauseHandler)
+	r.Post("/resume", ResumeHandler)
+	r.Post("/adjourn", AdjournHandler)
+	r.Get("/resume/{roomID}/{token}", ResumeLinkHandler)
+
+	// JSON API for scripts and other clients
+	r.Route(api.BasePath, apiRoutes)
+	r.Get("/api/openapi.json", OpenAPIHandler)
+
 	// Chat endpoints
 	r.Handle("/room/{roomID}/chat/", websocket.Handler(ChatWebsocketHandler))
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-	port = ":" + port
-	log.Fatal(http.ListenAndServe(port, r))
+	return r
 }
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"yamb/broadcaster"
+	"yamb/game"
+	"yamb/views"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// PauseHandler votes for (agree=true) or declines (agree=false) pausing the game
+func PauseHandler(w http.ResponseWriter, r *http.Request) {
+	voteHandler(w, r, game.StatePaused, broadcaster.GamePaused)
+}
+
+// ResumeHandler votes for (agree=true) or declines (agree=false) continuing a paused game
+func ResumeHandler(w http.ResponseWriter, r *http.Request) {
+	voteHandler(w, r, game.StatePlaying, broadcaster.GameResumed)
+}
+
+func voteHandler(w http.ResponseWriter, r *http.Request, to game.State, done broadcaster.EventName) {
+	roomID := r.FormValue("room_id")
+	room, ok := findRoom(roomID)
+	if !ok {
+		HxError(w, "room does not exist", 404)
+		return
+	}
+
+	playerCookie, err := r.Cookie("player_id")
+	if err != nil {
+		HxError(w, "no player cookie", http.StatusForbidden)
+		log.Println("no player cookie:", err)
+		return
+	}
+	playerID := room.PlayerFor(playerCookie.Value)
+
+	moved := false
+	if r.FormValue("agree") == "true" {
+		moved, err = room.Vote(playerID, to)
+	} else {
+		err = room.DeclineVote(playerID)
+	}
+	if err != nil {
+		HxError(w, fmt.Sprintf("could not vote: %v", err), errorStatus(err))
+		log.Println("error voting:", err)
+		return
+	}
+
+	if err := saveRoom(room); err != nil {
+		HxError(w, err.Error(), errorStatus(err))
+		return
+	}
+	if moved {
+		room.Broadcaster.Broadcast(broadcaster.Event{Name: done, Data: room.VotePayload()})
+		notifyTurn(room)
+	} else {
+		room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.PauseVoted, Data: room.VotePayload()})
+	}
+
+	renderLobby(w, r, room, playerID)
+}
+
+// AdjournHandler stops a paused game until someone uses their resume link
+func AdjournHandler(w http.ResponseWriter, r *http.Request) {
+	roomID := r.FormValue("room_id")
+	room, ok := findRoom(roomID)
+	if !ok {
+		HxError(w, "room does not exist", 404)
+		return
+	}
+
+	playerCookie, err := r.Cookie("player_id")
+	if err != nil {
+		HxError(w, "no player cookie", http.StatusForbidden)
+		log.Println("no player cookie:", err)
+		return
+	}
+	playerID := room.PlayerFor(playerCookie.Value)
+
+	err = room.Adjourn(playerID)
+	if err != nil {
+		HxError(w, fmt.Sprintf("could not adjourn game: %v", err), errorStatus(err))
+		log.Println("error adjourning game:", err)
+		return
+	}
+
+	if err := saveRoom(room); err != nil {
+		HxError(w, err.Error(), errorStatus(err))
+		return
+	}
+
+	room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.GameAdjourned, Data: room.VotePayload()})
+
+	renderLobby(w, r, room, playerID)
+}
+
+// ResumeLinkURLHandler renders the resume link of the player, only their
+// browser can make it from its cookie
+func ResumeLinkURLHandler(w http.ResponseWriter, r *http.Request) {
+	room, ok := findRoom(chi.URLParam(r, "roomID"))
+	if !ok {
+		HxError(w, "room does not exist", 404)
+		return
+	}
+
+	token := ""
+	if playerCookie, err := r.Cookie("player_id"); err == nil {
+		token = room.ResumeToken(playerCookie.Value)
+	}
+
+	err := views.ResumeLink(room.ID, token, getLang(r)).Render(r.Context(), w)
+	if err != nil {
+		HxError(w, "could not render resume link", http.StatusInternalServerError)
+		log.Println("error rendering resume link:", err)
+		return
+	}
+}
+
+// ResumeLinkHandler brings the player back into an adjourned game, the link
+// names the room so that any instance can load it from the store
+func ResumeLinkHandler(w http.ResponseWriter, r *http.Request) {
+	room, ok := findRoom(chi.URLParam(r, "roomID"))
+	if !ok {
+		HxError(w, "resume link is not valid", 404)
+		return
+	}
+	playerID, back, err := room.ResumeWith(chi.URLParam(r, "token"))
+	if err != nil {
+		HxError(w, "resume link is not valid", 404)
+		return
+	}
+
+	// the seat moves to this browser, whatever it played from before
+	if err := room.Rebind(playerID, ensurePlayerToken(w, r)); err != nil {
+		HxError(w, "resume link is not valid", 404)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:  "room_id",
+		Value: room.ID,
+		Path:  "/",
+	})
+
+	if err := saveRoom(room); err != nil {
+		HxError(w, err.Error(), errorStatus(err))
+		return
+	}
+	// the game comes back paused, playing needs everyone's vote
+	if back {
+		room.Broadcaster.Broadcast(broadcaster.Event{Name: broadcaster.GamePaused, Data: room.VotePayload()})
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/room/%s", room.ID), http.StatusSeeOther)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"yamb/broadcaster"
+	"yamb/game"
+	"yamb/store"
+)
+
+// where rooms are saved after every action, nil keeps them in memory only
+var roomStore store.RoomStore
+
+// carries the room events between server instances, nil when there is only one
+var eventBackend broadcaster.Backend
+
+// errRoomChanged fails an action that came too late: another instance changed
+// the room first, the change is undone and the player can try again
+var errRoomChanged = failed(http.StatusConflict, "the room changed in the meantime, try again")
+
+// errRoomNotSaved fails an action the store did not take, the other
+// instances do not see it
+var errRoomNotSaved = failed(http.StatusInternalServerError, "could not save the room, try again")
+
+// saveRoom stores the room after a change, the caller must not announce the
+// change when it fails. When another instance saved the room first the room
+// is reloaded and errRoomChanged returned.
+func saveRoom(room *game.Room) error {
+	if roomStore == nil {
+		return nil
+	}
+	err := roomStore.Save(room)
+	if errors.Is(err, store.ErrConflict) {
+		if saved, err := roomStore.Load(room.ID); err != nil {
+			log.Println("error reloading room:", err)
+		} else if saved != nil {
+			room.Refresh(saved)
+		}
+		return errRoomChanged
+	}
+	if err != nil {
+		log.Println("error saving room:", err)
+		return errRoomNotSaved
+	}
+	return nil
+}
+
+func deleteRoom(roomID string) {
+	if roomStore == nil {
+		return
+	}
+	if err := roomStore.Delete(roomID); err != nil {
+		log.Println("error deleting room:", err)
+	}
+}
+
+// random room ids tried before giving up, there are a million of them
+const roomIDTries = 100
+
+// randomRoomID returns a room id that may be taken
+var randomRoomID = func() string {
+	return fmt.Sprintf("%06d", rand.Intn(1000000))
+}
+
+// addRoom gives the new room an id no other room has, in memory or in the
+// store, saves it and keeps it
+func addRoom(room *game.Room) error {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	for range roomIDTries {
+		room.ID = randomRoomID()
+		if _, ok := rooms[room.ID]; ok {
+			continue
+		}
+		if roomStore != nil {
+			saved, err := roomStore.Load(room.ID)
+			if err != nil {
+				return err
+			}
+			if saved != nil {
+				continue
+			}
+			// another instance may pick the id at the same time, the first
+			// save wins
+			err = roomStore.Save(room)
+			if errors.Is(err, store.ErrConflict) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+		connectRoom(room)
+		rooms[room.ID] = room
+		return nil
+	}
+	return errors.New("no free room id, try again")
+}
+
+// loadRooms puts the saved rooms back into rooms
+func loadRooms() error {
+	saved, err := roomStore.LoadAll()
+	if err != nil {
+		return err
+	}
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	for _, room := range saved {
+		connectRoom(room)
+		rooms[room.ID] = room
+	}
+	log.Printf("restored %d rooms", len(saved))
+	return nil
+}
+
+// findRoom returns the room from memory, or from the store when another
+// instance created it
+func findRoom(roomID string) (*game.Room, bool) {
+	roomsMu.Lock()
+	defer roomsMu.Unlock()
+	if room, ok := rooms[roomID]; ok {
+		return room, true
+	}
+	if roomStore == nil {
+		return nil, false
+	}
+	room, err := roomStore.Load(roomID)
+	if err != nil {
+		log.Println("error loading room:", err)
+		return nil, false
+	}
+	if room == nil {
+		return nil, false
+	}
+	connectRoom(room)
+	rooms[roomID] = room
+	return room, true
+}
+
+// connectRoom shares the events of the room with the other instances
+func connectRoom(room *game.Room) {
+	if eventBackend == nil {
+		return
+	}
+	room.Broadcaster.OnRemote = func(ev broadcaster.Event) {
+		refreshRoom(room, ev)
+	}
+	err := room.Broadcaster.Connect(eventBackend, "yamb:room:"+room.ID+":events")
+	if err != nil {
+		log.Println("error connecting room events:", err)
+	}
+}
+
+// refreshRoom catches up with a change another instance made to the room,
+// it saved the room before publishing the event
+func refreshRoom(room *game.Room, ev broadcaster.Event) {
+	if ev.Name == broadcaster.RoomCancelled {
+		roomsMu.Lock()
+		delete(rooms, room.ID)
+		roomsMu.Unlock()
+		return
+	}
+	// presence does not change the room
+	if ev.Name.Topic() == broadcaster.TopicPresence {
+		return
+	}
+	saved, err := roomStore.Load(room.ID)
+	if err != nil {
+		log.Println("error reloading room:", err)
+		return
+	}
+	if saved != nil {
+		room.Refresh(saved)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"yamb/game"
+	"yamb/resp"
+	"yamb/resp/resptest"
+	"yamb/store"
+)
+
+// a change to a room another instance saved in the meantime is refused, the
+// room catches up with the other instance instead
+func TestSaveRoomConflict(t *testing.T) {
+	srv := resptest.NewServer(t, "")
+	stores := make([]*store.RedisStore, 2)
+	for i := range stores {
+		client, err := resp.Dial(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { client.Close() })
+		stores[i] = store.NewRedisStore(client)
+	}
+	roomStore = stores[0]
+	t.Cleanup(func() { roomStore = nil })
+
+	room := game.NewRoom(game.Mode1v1, "6", game.RulesClassic)
+	room.ID = "123456"
+	if err := saveRoom(room); err != nil {
+		t.Fatal(err)
+	}
+	elsewhere, err := stores[1].Load(room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elsewhere.ChatHistory = append(elsewhere.ChatHistory, game.NewChatMessage("p", "elsewhere"))
+	if err := stores[1].Save(elsewhere); err != nil {
+		t.Fatal(err)
+	}
+
+	room.ChatHistory = append(room.ChatHistory, game.NewChatMessage("p", "here"))
+	if err := saveRoom(room); err != errRoomChanged {
+		t.Fatalf("saveRoom = %v, want %v", err, errRoomChanged)
+	}
+	if len(room.ChatHistory) != 1 || room.ChatHistory[0].Message != "elsewhere" {
+		t.Errorf("room did not catch up: %+v", room.ChatHistory)
+	}
+
+	room.ChatHistory = append(room.ChatHistory, game.NewChatMessage("p", "here"))
+	if err := saveRoom(room); err != nil {
+		t.Errorf("saveRoom after catching up = %v", err)
+	}
+}
+
+// a new room never takes the id of a room in memory or in the store
+func TestAddRoom(t *testing.T) {
+	srv := resptest.NewServer(t, "")
+	stores := make([]*store.RedisStore, 2)
+	for i := range stores {
+		client, err := resp.Dial(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { client.Close() })
+		stores[i] = store.NewRedisStore(client)
+	}
+	roomStore = stores[0]
+	roomsMu.Lock()
+	rooms = map[string]*game.Room{"000001": game.NewRoom(game.Mode1v1, "6", game.RulesClassic)}
+	roomsMu.Unlock()
+	ids, random := []string{"000001", "000002", "000003"}, randomRoomID
+	randomRoomID = func() string {
+		id := ids[0]
+		ids = ids[1:]
+		return id
+	}
+	t.Cleanup(func() {
+		roomStore = nil
+		randomRoomID = random
+	})
+
+	// another instance created 000002
+	elsewhere := game.NewRoom(game.Mode1v1, "6", game.RulesClassic)
+	elsewhere.ID = "000002"
+	if err := stores[1].Save(elsewhere); err != nil {
+		t.Fatal(err)
+	}
+
+	room := game.NewRoom(game.Mode1v1, "6", game.RulesClassic)
+	if err := addRoom(room); err != nil {
+		t.Fatal(err)
+	}
+	if room.ID != "000003" {
+		t.Errorf("new room got id %s, want 000003", room.ID)
+	}
+	if found, ok := findRoom("000003"); !ok || found != room {
+		t.Error("new room is not kept")
+	}
+	if saved, err := stores[1].Load("000003"); err != nil || saved == nil {
+		t.Errorf("new room is not saved: %v", err)
+	}
+}
+
+// brokenStore refuses every save
+type brokenStore struct {
+	store.RoomStore
+}
+
+func (brokenStore) Save(*game.Room) error {
+	return errors.New("disk full")
+}
+
+// a save the store does not take fails the action with a server error
+func TestSaveRoomFailure(t *testing.T) {
+	roomStore = brokenStore{}
+	t.Cleanup(func() { roomStore = nil })
+
+	room := game.NewRoom(game.Mode1v1, "6", game.RulesClassic)
+	room.ID = "123456"
+	if err := saveRoom(room); errorStatus(err) != http.StatusInternalServerError {
+		t.Errorf("saveRoom = %v, want a server error", err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"yamb/game"
+	"yamb/record"
+	"yamb/stats"
+	"yamb/views"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const ratingHistoryLength = 20
+
+// soloOwner returns who the personal bests of the player belong to, their
+// account or else the token of their browser, player ids change every room
+func soloOwner(room *game.Room, playerID string) string {
+	p := room.GetPlayerByID(playerID)
+	if p == nil {
+		return ""
+	}
+	if p.AccountID != "" {
+		return "account:" + p.AccountID
+	}
+	return "token:" + p.TokenHash
+}
+
+// archiveGame keeps the finished game in the history of the players with
+// accounts, solo games go to the personal bests of their player instead
+func archiveGame(room *game.Room) {
+	rec, err := record.New(room.ID, room.Events())
+	if err != nil {
+		log.Println("error creating game record:", err)
+		return
+	}
+	if rec.Mode == game.ModeSolo {
+		if err := accountStore.SaveSolo(soloOwner(room, rec.Players[0].ID), rec); err != nil {
+			log.Println("error saving solo game:", err)
+		}
+		return
+	}
+	hasAccount := slices.ContainsFunc(rec.Players, func(p record.PlayerRecord) bool {
+		return p.AccountID != ""
+	})
+	if !hasAccount {
+		return
+	}
+	if err := accountStore.SaveGame(rec); err != nil {
+		log.Println("error saving game history:", err)
+	}
+	if err := accountStore.RateGame(rec); err != nil {
+		log.Println("error updating ratings:", err)
+	}
+}
+
+func ProfileHandler(w http.ResponseWriter, r *http.Request) {
+	username := chi.URLParam(r, "username")
+	acc, err := accountStore.ByUsername(username)
+	if err != nil {
+		HxError(w, "could not load player", http.StatusInternalServerError)
+		log.Println("error loading account:", err)
+		return
+	}
+	if acc == nil {
+		HxError(w, "player does not exist", 404)
+		return
+	}
+
+	games, err := accountStore.Games(acc.ID)
+	if err != nil {
+		HxError(w, "could not load games", http.StatusInternalServerError)
+		log.Println("error loading games:", err)
+		return
+	}
+
+	ratings, err := accountStore.Ratings(acc.ID)
+	if err != nil {
+		HxError(w, "could not load ratings", http.StatusInternalServerError)
+		log.Println("error loading ratings:", err)
+		return
+	}
+	history, err := accountStore.RatingHistory(acc.ID)
+	if err != nil {
+		HxError(w, "could not load rating history", http.StatusInternalServerError)
+		log.Println("error loading rating history:", err)
+		return
+	}
+	// newest first, only the recent games
+	slices.Reverse(history)
+	if len(history) > ratingHistoryLength {
+		history = history[:ratingHistoryLength]
+	}
+
+	lang := getLang(r)
+
+	err = views.ProfilePage(lang, acc.Username, stats.Compute(acc.ID, games), ratings, history).Render(r.Context(), w)
+	if err != nil {
+		HxError(w, "could not render profile", http.StatusInternalServerError)
+		log.Println("error rendering profile:", err)
+		return
+	}
+}
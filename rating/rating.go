@@ -0,0 +1,144 @@
+// Package rating computes Elo ratings of accounts from finished game records.
+//
+// A game with more than two sides is rated as a round robin of one-on-one
+// matches between every pair of sides, decided by their rank in the game
+// (equal ranks are a draw). The K factor is split between the matches so a
+// game moves a rating about as much as a 1v1 game does. In 2v2 the partners
+// form one side, rated with their average rating and ranked by their summed
+// totals, and both get the change of the side.
+package rating
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"yamb/game"
+	"yamb/record"
+)
+
+const (
+	Initial = 1500.0
+	K       = 32.0
+)
+
+// ratings are kept separately for every mode and dice count
+type Pool struct {
+	Mode string
+	Dice int
+}
+
+func PoolOf(rec *record.Record) Pool {
+	return Pool{Mode: rec.Mode, Dice: rec.Dice}
+}
+
+func (p Pool) Key() string {
+	return fmt.Sprintf("%s-%d", p.Mode, p.Dice)
+}
+
+// every pool shown on the leaderboard
+func Pools() []Pool {
+	pools := []Pool{}
+	for _, mode := range []string{game.Mode1v1, game.Mode1v1v1, game.Mode2v2} {
+		for _, dice := range []int{6, 5} {
+			pools = append(pools, Pool{Mode: mode, Dice: dice})
+		}
+	}
+	return pools
+}
+
+type Change struct {
+	AccountID string
+	Before    float64
+	After     float64
+}
+
+func (c Change) Delta() float64 {
+	return c.After - c.Before
+}
+
+// Expected is the expected score of a against b, between 0 and 1
+func Expected(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+// Rated reports whether the game counts for the ratings,
+// only games where every player has an account are rated
+func Rated(rec *record.Record) bool {
+	return len(rec.Players) > 1 && !slices.ContainsFunc(rec.Players, func(p record.PlayerRecord) bool {
+		return p.AccountID == ""
+	})
+}
+
+type side struct {
+	accounts []string
+	rating   float64
+	total    int
+}
+
+// Update returns the rating change of every player of a rated game,
+// current holds the ratings by account id and missing accounts start at Initial
+func Update(rec *record.Record, current map[string]float64) []Change {
+	if !Rated(rec) {
+		return nil
+	}
+	rating := func(accountID string) float64 {
+		if r, ok := current[accountID]; ok {
+			return r
+		}
+		return Initial
+	}
+
+	sides := sidesOf(rec)
+	for i := range sides {
+		sum := 0.0
+		for _, id := range sides[i].accounts {
+			sum += rating(id)
+		}
+		sides[i].rating = sum / float64(len(sides[i].accounts))
+	}
+
+	k := K / float64(len(sides)-1)
+	changes := []Change{}
+	for i, s := range sides {
+		delta := 0.0
+		for j, o := range sides {
+			if i == j {
+				continue
+			}
+			delta += k * (score(s.total, o.total) - Expected(s.rating, o.rating))
+		}
+		for _, id := range s.accounts {
+			before := rating(id)
+			changes = append(changes, Change{AccountID: id, Before: before, After: before + delta})
+		}
+	}
+	return changes
+}
+
+// the players grouped into the sides that compete against each other
+func sidesOf(rec *record.Record) []side {
+	sides := []side{}
+	index := map[int]int{} // side of the record -> index in sides
+	for i, p := range rec.Players {
+		j, ok := index[rec.Side(i)]
+		if !ok {
+			j = len(sides)
+			index[rec.Side(i)] = j
+			sides = append(sides, side{})
+		}
+		sides[j].accounts = append(sides[j].accounts, p.AccountID)
+		sides[j].total += p.Total
+	}
+	return sides
+}
+
+// result of a match between two totals, a draw is half a win
+func score(total, other int) float64 {
+	switch {
+	case total > other:
+		return 1
+	case total < other:
+		return 0
+	}
+	return 0.5
+}
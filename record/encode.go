@@ -0,0 +1,62 @@
+package record
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+func (rec *Record) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rec)
+}
+
+// csv columns, one line per scorecard cell plus a "total" line per player,
+// empty cells have an empty score
+var csvHeader = []string{
+	"schema", "room_id", "mode", "dice", "rules", "started_at", "finished_at",
+	"player_id", "username", "team", "rank", "row", "col", "score",
+}
+
+// WriteCSV writes the scorecards of the record (moves are only in the JSON record)
+func (rec *Record) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	game := []string{
+		strconv.Itoa(rec.Schema),
+		rec.RoomID,
+		rec.Mode,
+		strconv.Itoa(rec.Dice),
+		rec.Rules,
+		rec.StartedAt.Format(time.RFC3339),
+		rec.FinishedAt.Format(time.RFC3339),
+	}
+	for _, p := range rec.Players {
+		player := []string{p.ID, p.Username, strconv.Itoa(p.Team), strconv.Itoa(p.Rank)}
+		for _, c := range p.Cells {
+			score := ""
+			if c.Score != nil {
+				score = strconv.Itoa(*c.Score)
+			}
+			if err := cw.Write(csvLine(game, player, c.Row, c.Col, score)); err != nil {
+				return err
+			}
+		}
+		if err := cw.Write(csvLine(game, player, "total", "", strconv.Itoa(p.Total))); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvLine(game, player []string, row, col, score string) []string {
+	line := append([]string{}, game...)
+	line = append(line, player...)
+	return append(line, row, col, score)
+}
@@ -0,0 +1,39 @@
+package record
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"yamb/game"
+)
+
+// exports are shared freely, they may name players by id but must not carry
+// the tokens they act with
+func TestExportsShowNoTokens(t *testing.T) {
+	r, tokens := finishedGame(t, game.Mode1v1, game.RulesClassic)
+	rec, err := New(r.ID, r.Events())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := rec.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := rec.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range r.Players {
+		if !strings.Contains(buf.String(), p.ID) {
+			t.Errorf("exports do not name player %s", p.ID)
+		}
+		if strings.Contains(buf.String(), p.TokenHash) {
+			t.Errorf("exports show the token hash of player %s", p.ID)
+		}
+	}
+	for _, token := range tokens {
+		if strings.Contains(buf.String(), token) {
+			t.Errorf("exports show the token %s", token)
+		}
+	}
+}
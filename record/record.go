@@ -0,0 +1,183 @@
+// Package record converts finished games to portable game records.
+//
+// A record (schema version 1) holds the settings of the game, every player in
+// turn order with their full scorecard and the moves that led to it:
+//
+//	{
+//	  "schema": 1,
+//	  "room_id": "123456",
+//	  "mode": "1v1",            // 1v1, 1v1v1 or 2v2
+//	  "dice": 6,                // 5 or 6
+//	  "rules": "classic",       // classic or extended
+//	  "started_at": "2025-01-01T20:00:00Z",
+//	  "finished_at": "2025-01-01T20:45:00Z",
+//	  "players": [{
+//	    "id": "...", "username": "...", "account_id": "...", "team": 0, "rank": 1, "total": 1234,
+//	    "cells": [{"row": "1", "col": "t2b", "score": 3}, ...]  // every row x column, sums included, null if empty
+//	  }],
+//	  "moves": [{
+//	    "seq": 2, "at": "...", "type": "rolled", "player_id": "...",
+//	    "dice": [1, 2, 3, 4, 5, 6], "held": [false, ...], "die": 0, "row": "", "col": "", "score": 0
+//	  }]
+//	}
+//
+// Move types are the game event types (rolled, toggled, selected, announced,
+// written, paused, resumed, adjourned), the start of the game is described
+// by the record itself. New fields may be added within a schema version,
+// removing or changing a field bumps the version.
+package record
+
+import (
+	"errors"
+	"slices"
+	"time"
+	"yamb/game"
+)
+
+const SchemaVersion = 1
+
+type Record struct {
+	Schema     int            `json:"schema"`
+	RoomID     string         `json:"room_id"`
+	Mode       string         `json:"mode"`
+	Dice       int            `json:"dice"`
+	Rules      string         `json:"rules"`
+	StartedAt  time.Time      `json:"started_at"`
+	FinishedAt time.Time      `json:"finished_at"`
+	Players    []PlayerRecord `json:"players"` // turn order
+	Moves      []Move         `json:"moves"`
+}
+
+type PlayerRecord struct {
+	ID        string `json:"id"` // public id in the room, never the player's token
+	Username  string `json:"username"`
+	AccountID string `json:"account_id,omitempty"` // empty for anonymous players
+	Team      int    `json:"team"`
+	Rank      int    `json:"rank"` // 1 is the winner, equal totals share the rank
+	Total     int    `json:"total"`
+	Cells     []Cell `json:"cells"`
+}
+
+type Cell struct {
+	Row   string `json:"row"`
+	Col   string `json:"col"`
+	Score *int   `json:"score"`
+}
+
+type Move struct {
+	Seq      int            `json:"seq"`
+	At       time.Time      `json:"at"`
+	Type     game.EventType `json:"type"`
+	PlayerID string         `json:"player_id"`
+	Dice     []int          `json:"dice,omitempty"`
+	Held     []bool         `json:"held,omitempty"`
+	Die      int            `json:"die"`
+	Row      string         `json:"row,omitempty"`
+	Col      string         `json:"col,omitempty"`
+	Score    int            `json:"score"`
+}
+
+var ErrNotFinished = errors.New("game is not finished")
+
+// New creates the record of the last game in the event log
+func New(roomID string, log []game.GameEvent) (*Record, error) {
+	events := game.LastGame(log)
+	if len(events) == 0 {
+		return nil, ErrNotFinished
+	}
+	room, err := game.Replay(events)
+	if err != nil {
+		return nil, err
+	}
+	if room.State != game.StateFinished {
+		return nil, ErrNotFinished
+	}
+
+	rec := &Record{
+		Schema:     SchemaVersion,
+		RoomID:     roomID,
+		Mode:       room.Mode,
+		Dice:       room.NumOfDice,
+		Rules:      room.Rules,
+		StartedAt:  events[0].At,
+		FinishedAt: events[len(events)-1].At,
+		Players:    make([]PlayerRecord, len(room.Players)),
+		Moves:      make([]Move, 0, len(events)-1),
+	}
+	for i, p := range room.Players {
+		rec.Players[i] = playerRecord(p)
+	}
+	rec.rank()
+	for _, ev := range events[1:] {
+		rec.Moves = append(rec.Moves, Move{
+			Seq:      ev.Seq,
+			At:       ev.At,
+			Type:     ev.Type,
+			PlayerID: ev.PlayerID,
+			Dice:     ev.Dice,
+			Held:     ev.Held,
+			Die:      ev.Die,
+			Row:      ev.Row,
+			Col:      ev.Col,
+			Score:    ev.Score,
+		})
+	}
+	return rec, nil
+}
+
+func playerRecord(p *game.Player) PlayerRecord {
+	sc := p.ScoreCard
+	cells := []Cell{}
+	for _, row := range sc.Rows {
+		for _, col := range sc.Columns {
+			cells = append(cells, Cell{Row: row.ID, Col: col.ID, Score: sc.Scores[row.ID][col.ID]})
+		}
+	}
+	return PlayerRecord{
+		ID:        p.ID,
+		Username:  p.Username,
+		AccountID: p.AccountID,
+		Team:      int(p.Team),
+		Total:     sc.TotalScore(),
+		Cells:     cells,
+	}
+}
+
+// Won reports whether the player at idx won the game, in 2v2 the side with
+// the higher total wins. Equal totals share the win.
+func (rec *Record) Won(idx int) bool {
+	totals := map[int]int{}
+	for i, p := range rec.Players {
+		totals[rec.Side(i)] += p.Total
+	}
+	own := totals[rec.Side(idx)]
+	for _, total := range totals {
+		if total > own {
+			return false
+		}
+	}
+	return true
+}
+
+// Side is the side the player at idx competes on. Teams follow the seats
+// (0 to 3), in 2v2 partners sit across the table so the seats alternate
+// between the two sides; in the other modes every player is a side.
+func (rec *Record) Side(idx int) int {
+	if rec.Mode == game.Mode2v2 {
+		return rec.Players[idx].Team % 2
+	}
+	return idx
+}
+
+// rank sets the rank of every player from the totals
+func (rec *Record) rank() {
+	totals := make([]int, len(rec.Players))
+	for i, p := range rec.Players {
+		totals[i] = p.Total
+	}
+	slices.Sort(totals)
+	slices.Reverse(totals)
+	for i := range rec.Players {
+		rec.Players[i].Rank = slices.Index(totals, rec.Players[i].Total) + 1
+	}
+}
@@ -0,0 +1,119 @@
+package record
+
+import (
+	"strconv"
+	"testing"
+	"yamb/game"
+)
+
+// finishedGame plays a whole game of the mode, every player writing the first
+// cell the dice allow, and returns the room with the tokens of its players
+func finishedGame(t *testing.T, mode, rules string) (*game.Room, []string) {
+	t.Helper()
+	r := game.NewRoom(mode, "6", rules)
+	r.ID = "123456"
+	var tokens []string
+	for i := 0; !r.IsFull() && r.InLobby(); i++ {
+		token := game.NewToken()
+		if i == 0 {
+			r.SetHost(token)
+		}
+		p := r.NewPlayer(token, "player"+strconv.Itoa(i+1))
+		if err := r.AddPlayer(p); err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+	if r.InLobby() {
+		for _, p := range r.Players {
+			if err := r.SetReady(p.ID, true); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := r.Start(r.HostID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for r.GetState() == game.StatePlaying {
+		playTurn(t, r, r.CurrentPlayerID())
+	}
+	return r, tokens
+}
+
+func playTurn(t *testing.T, r *game.Room, playerID string) {
+	t.Helper()
+	if err := r.RollDice(playerID); err != nil {
+		t.Fatal(err)
+	}
+	p := r.GetPlayerByID(playerID)
+	for _, col := range p.ScoreCard.Columns {
+		for _, row := range p.ScoreCard.Rows {
+			if p.ScoreCard.Scores[row.ID][col.ID] == nil && write(r, playerID, row.ID, col.ID) {
+				return
+			}
+		}
+	}
+	t.Fatalf("%s cannot write anything", playerID)
+}
+
+// write holds the dice the row needs and writes the cell
+func write(r *game.Room, playerID, row, col string) bool {
+	if r.SelectCell(playerID, row, col) != nil {
+		return false
+	}
+	if col == game.Announced && r.Announce(playerID) != nil {
+		return false
+	}
+	if n, err := strconv.Atoi(row); err == nil {
+		hold(r, playerID, func(_, v int) bool { return v == n })
+	} else if row == game.Quads {
+		hold(r, playerID, func(i, _ int) bool { return i < 4 })
+	} else {
+		hold(r, playerID, func(i, _ int) bool { return i < 5 })
+	}
+	_, err := r.WriteScore(playerID)
+	return err == nil
+}
+
+func hold(r *game.Room, playerID string, want func(i, v int) bool) {
+	for i, v := range r.Dice.Values {
+		if r.Dice.Held[i] != want(i, v) {
+			r.ToggleDie(playerID, i)
+		}
+	}
+}
+
+// the teams are those the rooms give their players, the totals are replaced
+// to decide the winner
+func TestWon(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   string
+		totals []int
+		want   []bool
+	}{
+		{"1v1", game.Mode1v1, []int{700, 650}, []bool{true, false}},
+		{"1v1 tie", game.Mode1v1, []int{700, 700}, []bool{true, true}},
+		{"1v1v1", game.Mode1v1v1, []int{600, 800, 700}, []bool{false, true, false}},
+		// the best player is on the side with the lower total
+		{"2v2", game.Mode2v2, []int{900, 600, 300, 700}, []bool{false, true, false, true}},
+		{"2v2 tie", game.Mode2v2, []int{900, 600, 300, 600}, []bool{true, true, true, true}},
+	}
+	for _, tt := range tests {
+		r, _ := finishedGame(t, tt.mode, game.RulesClassic)
+		rec, err := New(r.ID, r.Events())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range rec.Players {
+			rec.Players[i].Total = tt.totals[i]
+		}
+		rec.rank()
+		for i, want := range tt.want {
+			if got := rec.Won(i); got != want {
+				t.Errorf("%s: player %d (team %d) won = %v, want %v", tt.name, i, rec.Players[i].Team, got, want)
+			}
+		}
+	}
+}
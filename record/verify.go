@@ -0,0 +1,133 @@
+package record
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"yamb/game"
+)
+
+// Read decodes a JSON game record
+func Read(r io.Reader) (*Record, error) {
+	rec := &Record{}
+	if err := json.NewDecoder(r).Decode(rec); err != nil {
+		return nil, err
+	}
+	if rec.Schema != SchemaVersion {
+		return nil, fmt.Errorf("unsupported schema version %d", rec.Schema)
+	}
+	return rec, nil
+}
+
+// Report is the outcome of verifying a game record
+type Report struct {
+	Moves    int      // moves replayed before the first illegal one
+	Problems []string // illegal moves and scores that do not match the replay
+}
+
+func (rep *Report) OK() bool {
+	return len(rep.Problems) == 0
+}
+
+func (rep *Report) problem(format string, args ...any) {
+	rep.Problems = append(rep.Problems, fmt.Sprintf(format, args...))
+}
+
+// Verify replays the moves of the record through the game rules and compares
+// the resulting scorecards (sums and totals included) with the recorded ones
+func Verify(rec *Record) *Report {
+	rep := &Report{}
+
+	events := []game.GameEvent{rec.startedEvent()}
+	for _, m := range rec.Moves {
+		events = append(events, m.event())
+	}
+	room, err := game.Replay(events)
+	var replayErr *game.ReplayError
+	if errors.As(err, &replayErr) {
+		// the first event is the start of the game, not a move
+		rep.Moves = max(replayErr.Index-1, 0)
+		rep.problem("%v", err)
+		return rep
+	}
+	if err != nil {
+		rep.problem("%v", err)
+		return rep
+	}
+	rep.Moves = len(rec.Moves)
+
+	if room.State != game.StateFinished {
+		rep.problem("game is not finished")
+	}
+	for i, p := range room.Players {
+		recorded := rec.Players[i]
+		cells := map[[2]string]*int{}
+		for _, c := range recorded.Cells {
+			cells[[2]string{c.Row, c.Col}] = c.Score
+		}
+		for _, row := range p.ScoreCard.Rows {
+			for _, col := range p.ScoreCard.Columns {
+				want := p.ScoreCard.Scores[row.ID][col.ID]
+				got, ok := cells[[2]string{row.ID, col.ID}]
+				if !ok {
+					rep.problem("%s: cell %s/%s is missing", recorded.Username, row.ID, col.ID)
+					continue
+				}
+				if !sameScore(got, want) {
+					rep.problem("%s: cell %s/%s is %s, replay gives %s", recorded.Username, row.ID, col.ID, scoreText(got), scoreText(want))
+				}
+			}
+		}
+		if total := p.ScoreCard.TotalScore(); total != recorded.Total {
+			rep.problem("%s: total is %d, replay gives %d", recorded.Username, recorded.Total, total)
+		}
+	}
+	return rep
+}
+
+// startedEvent seats the players of the record in their turn order
+func (rec *Record) startedEvent() game.GameEvent {
+	seats := make([]game.Seat, len(rec.Players))
+	for i, p := range rec.Players {
+		seats[i] = game.Seat{ID: p.ID, Username: p.Username, Team: game.Team(p.Team), AccountID: p.AccountID}
+	}
+	return game.GameEvent{
+		Seq:       1,
+		At:        rec.StartedAt,
+		Type:      game.EventStarted,
+		Mode:      rec.Mode,
+		Rules:     rec.Rules,
+		NumOfDice: rec.Dice,
+		Seats:     seats,
+	}
+}
+
+func (m Move) event() game.GameEvent {
+	return game.GameEvent{
+		Seq:      m.Seq,
+		At:       m.At,
+		Type:     m.Type,
+		PlayerID: m.PlayerID,
+		Dice:     m.Dice,
+		Held:     m.Held,
+		Die:      m.Die,
+		Row:      m.Row,
+		Col:      m.Col,
+		Score:    m.Score,
+	}
+}
+
+func sameScore(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func scoreText(score *int) string {
+	if score == nil {
+		return "empty"
+	}
+	return fmt.Sprint(*score)
+}
@@ -0,0 +1,72 @@
+package record
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"yamb/game"
+)
+
+func TestVerify(t *testing.T) {
+	r, _ := finishedGame(t, game.Mode1v1, game.RulesClassic)
+	rec, err := New(r.ID, r.Events())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported bytes.Buffer
+	if err := rec.WriteJSON(&exported); err != nil {
+		t.Fatal(err)
+	}
+	firstWrite := -1
+	for i, m := range rec.Moves {
+		if m.Type == game.EventWritten {
+			firstWrite = i
+			break
+		}
+	}
+
+	tests := []struct {
+		name    string
+		change  func(rec *Record)
+		moves   int    // moves replayed before the first illegal one
+		problem string // part of the first problem, empty when the record is legal
+	}{
+		{"legal", func(*Record) {}, len(rec.Moves), ""},
+		{"illegal write", func(rec *Record) {
+			// top to bottom starts at the ones
+			rec.Moves[firstWrite].Row, rec.Moves[firstWrite].Col = game.Yamb, game.TopToBottom
+		}, firstWrite, "(written)"},
+		{"wrong score", func(rec *Record) { rec.Moves[firstWrite].Score++ }, firstWrite, "does not match"},
+		{"bad sum", func(rec *Record) {
+			for i, c := range rec.Players[0].Cells {
+				if c.Row == game.Sum1 && c.Score != nil {
+					score := *c.Score + 1
+					rec.Players[0].Cells[i].Score = &score
+				}
+			}
+		}, len(rec.Moves), "cell sum1/"},
+		{"bad total", func(rec *Record) { rec.Players[1].Total++ }, len(rec.Moves), "total is"},
+		{"unfinished", func(rec *Record) { rec.Moves = rec.Moves[:len(rec.Moves)-1] }, len(rec.Moves) - 1, "not finished"},
+	}
+	for _, tt := range tests {
+		// every case starts from the exported record read back
+		read, err := Read(bytes.NewReader(exported.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tt.change(read)
+		rep := Verify(read)
+		if rep.Moves != tt.moves {
+			t.Errorf("%s: replayed %d moves, want %d", tt.name, rep.Moves, tt.moves)
+		}
+		if tt.problem == "" {
+			if !rep.OK() {
+				t.Errorf("%s: %v", tt.name, rep.Problems)
+			}
+			continue
+		}
+		if rep.OK() || !strings.Contains(rep.Problems[0], tt.problem) {
+			t.Errorf("%s: problems %q, want one about %q", tt.name, rep.Problems, tt.problem)
+		}
+	}
+}
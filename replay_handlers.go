@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"yamb/game"
+	"yamb/views"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func ReplayPageHandler(w http.ResponseWriter, r *http.Request) {
+	renderReplay(w, r, true)
+}
+
+// ReplayStepHandler renders a single move of the replay (prev/next/autoplay)
+func ReplayStepHandler(w http.ResponseWriter, r *http.Request) {
+	renderReplay(w, r, false)
+}
+
+func renderReplay(w http.ResponseWriter, r *http.Request, page bool) {
+	roomID := chi.URLParam(r, "roomID")
+	room, ok := findRoom(roomID)
+	if !ok {
+		HxError(w, "room does not exist", 404)
+		return
+	}
+
+	if !canAccessRoom(r, room) {
+		HxError(w, "private room", http.StatusForbidden)
+		return
+	}
+
+	events := game.LastGame(room.Events())
+	if len(events) == 0 {
+		HxError(w, "no game to replay", 404)
+		return
+	}
+
+	// step 0 is the start of the game, missing or invalid steps start from there
+	step, _ := strconv.Atoi(r.URL.Query().Get("step"))
+	step = max(0, min(step, len(events)-1))
+	autoplay := r.URL.Query().Get("autoplay") == "true" && step < len(events)-1
+
+	replayed, err := game.Replay(events[:step+1])
+	if err != nil {
+		HxError(w, "could not replay game", http.StatusInternalServerError)
+		log.Println("error replaying game:", err)
+		return
+	}
+
+	lang := getLang(r)
+	replay := views.ReplayState{
+		Room:     replayed,
+		Event:    events[step],
+		Step:     step,
+		Last:     len(events) - 1,
+		Autoplay: autoplay,
+	}
+
+	if page {
+		err = views.ReplayPage(roomID, lang, replay).Render(r.Context(), w)
+	} else {
+		err = views.ReplayStep(roomID, lang, replay).Render(r.Context(), w)
+	}
+	if err != nil {
+		HxError(w, "could not render replay", http.StatusInternalServerError)
+		log.Println("error rendering replay:", err)
+		return
+	}
+}
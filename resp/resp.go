@@ -0,0 +1,419 @@
+// Package resp is a minimal client for the Redis serialization protocol
+// (RESP2), just enough for string keys and pub/sub. It works with Redis and
+// the servers that speak its protocol (Valkey, KeyDB, Dragonfly, ...).
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const dialTimeout = 5 * time.Second
+
+// Error is an error reply of the server
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+// connection settings parsed from redis://[:password@]host:port[/db]
+type options struct {
+	addr     string
+	password string
+	db       int
+}
+
+func parseURL(rawURL string) (options, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return options{}, err
+	}
+	if u.Scheme != "redis" {
+		return options{}, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+	opts := options{addr: u.Host}
+	if u.Port() == "" {
+		opts.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if password, ok := u.User.Password(); ok {
+		opts.password = password
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		opts.db, err = strconv.Atoi(db)
+		if err != nil {
+			return options{}, fmt.Errorf("bad database number %q", db)
+		}
+	}
+	return opts, nil
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// dial connects, authenticates and selects the database
+func dial(opts options) (*conn, error) {
+	nc, err := net.DialTimeout("tcp", opts.addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &conn{Conn: nc, r: bufio.NewReader(nc)}
+	if opts.password != "" {
+		if err := c.setup("AUTH", opts.password); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	if opts.db != 0 {
+		if err := c.setup("SELECT", strconv.Itoa(opts.db)); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// setup runs a command of dial, an error reply fails it too
+func (c *conn) setup(args ...string) error {
+	reply, err := c.do(args...)
+	if err != nil {
+		return err
+	}
+	if e, ok := reply.(Error); ok {
+		return e
+	}
+	return nil
+}
+
+func (c *conn) do(args ...string) (any, error) {
+	if err := c.send(args...); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// send writes a command as an array of bulk strings
+func (c *conn) send(args ...string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	_, err := io.WriteString(c.Conn, b.String())
+	return err
+}
+
+// read returns the next reply: string, int64, nil, []any or Error
+func (c *conn) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return Error(body), nil
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unknown reply type %q", kind)
+}
+
+// Client runs commands over one connection, redialing when it breaks
+type Client struct {
+	opts options
+
+	mu   sync.Mutex
+	conn *conn
+}
+
+func Dial(rawURL string) (*Client, error) {
+	opts, err := parseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	c, err := dial(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{opts: opts, conn: c}, nil
+}
+
+// Do runs the command, error replies are returned as Error
+func (c *Client) Do(args ...string) (any, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+	reply, err := c.conn.do(args...)
+	if err != nil {
+		// the connection is in an unknown state, start over next time
+		c.drop()
+		return nil, err
+	}
+	if e, ok := reply.(Error); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+// ErrAborted is returned by Watch when a watched key changed before EXEC
+var ErrAborted = errors.New("transaction aborted, a watched key changed")
+
+// Tx is the connection of a Client while Watch runs, nobody else's commands
+// come in between
+type Tx struct {
+	conn   *conn
+	broken bool // left in a WATCH or MULTI, or the connection failed
+}
+
+// Do runs the command, error replies are returned as Error
+func (tx *Tx) Do(args ...string) (any, error) {
+	reply, err := tx.conn.do(args...)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := reply.(Error); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+// Watch is an optimistic transaction: it watches the keys and calls fn, which
+// reads with tx.Do (and may WATCH more keys before reading them) and returns
+// the commands to run. They run between MULTI and EXEC, all of them or, when
+// a watched key changed in the meantime, none and Watch returns ErrAborted.
+func (c *Client) Watch(keys []string, fn func(tx *Tx) ([][]string, error)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.connect(); err != nil {
+		return err
+	}
+	tx := &Tx{conn: c.conn}
+	err := watch(tx, keys, fn)
+	if tx.broken {
+		// start over with a new connection next time
+		c.drop()
+	}
+	return err
+}
+
+func watch(tx *Tx, keys []string, fn func(tx *Tx) ([][]string, error)) error {
+	if len(keys) > 0 {
+		if _, err := tx.Do(append([]string{"WATCH"}, keys...)...); err != nil {
+			tx.broken = true
+			return err
+		}
+	}
+	cmds, err := fn(tx)
+	if err != nil || len(cmds) == 0 {
+		if _, unwatchErr := tx.Do("UNWATCH"); unwatchErr != nil {
+			tx.broken = true
+			return errors.Join(err, unwatchErr)
+		}
+		return err
+	}
+	tx.broken = true
+	if _, err := tx.Do("MULTI"); err != nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		if _, err := tx.Do(cmd...); err != nil {
+			return err
+		}
+	}
+	reply, err := tx.Do("EXEC")
+	if err != nil {
+		return err
+	}
+	// EXEC ends the transaction whatever it replied
+	tx.broken = false
+	if reply == nil {
+		return ErrAborted
+	}
+	replies, _ := reply.([]any)
+	for _, r := range replies {
+		if e, ok := r.(Error); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// connect dials if there is no connection, caller must hold the lock
+func (c *Client) connect() error {
+	if c.conn != nil {
+		return nil
+	}
+	conn, err := dial(c.opts)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	return nil
+}
+
+// drop closes the connection, caller must hold the lock
+func (c *Client) drop() {
+	c.conn.Close()
+	c.conn = nil
+}
+
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// PubSub holds a connection in subscribe mode and passes the messages of the
+// subscribed channels to the handler, it reconnects and subscribes again when
+// the connection breaks
+type PubSub struct {
+	opts    options
+	handler func(channel string, payload []byte)
+
+	mu       sync.Mutex
+	conn     *conn
+	channels map[string]bool
+	closed   bool
+}
+
+func NewPubSub(rawURL string, handler func(channel string, payload []byte)) (*PubSub, error) {
+	opts, err := parseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	c, err := dial(opts)
+	if err != nil {
+		return nil, err
+	}
+	p := &PubSub{opts: opts, handler: handler, conn: c, channels: make(map[string]bool)}
+	go p.receive(c)
+	return p, nil
+}
+
+func (p *PubSub) Subscribe(channel string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.channels[channel] = true
+	// replies come through receive, a broken connection subscribes again on reconnect
+	return p.conn.send("SUBSCRIBE", channel)
+}
+
+func (p *PubSub) Unsubscribe(channel string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.channels, channel)
+	return p.conn.send("UNSUBSCRIBE", channel)
+}
+
+func (p *PubSub) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return p.conn.Close()
+}
+
+func (p *PubSub) receive(c *conn) {
+	for {
+		reply, err := c.read()
+		if err != nil {
+			c.Close()
+			if c = p.reconnect(); c == nil {
+				return
+			}
+			continue
+		}
+		// ["message", channel, payload], subscribe confirmations are skipped
+		msg, ok := reply.([]any)
+		if !ok || len(msg) != 3 || msg[0] != "message" {
+			continue
+		}
+		channel, _ := msg[1].(string)
+		payload, _ := msg[2].(string)
+		p.handler(channel, []byte(payload))
+	}
+}
+
+// reconnect dials until it succeeds or the PubSub is closed (returns nil)
+func (p *PubSub) reconnect() *conn {
+	backoff := 100 * time.Millisecond
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil
+		}
+		p.mu.Unlock()
+
+		c, err := dial(p.opts)
+		if err == nil {
+			p.mu.Lock()
+			if p.closed {
+				p.mu.Unlock()
+				c.Close()
+				return nil
+			}
+			p.conn = c
+			for channel := range p.channels {
+				err = errors.Join(err, c.send("SUBSCRIBE", channel))
+			}
+			p.mu.Unlock()
+			if err == nil {
+				return c
+			}
+			c.Close()
+		}
+		time.Sleep(backoff)
+		backoff = min(2*backoff, 5*time.Second)
+	}
+}
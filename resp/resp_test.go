@@ -0,0 +1,188 @@
+package resp
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"yamb/resp/resptest"
+)
+
+func TestParseURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want options
+		err  bool
+	}{
+		{"redis://localhost", options{addr: "localhost:6379"}, false},
+		{"redis://cache:6380", options{addr: "cache:6380"}, false},
+		{"redis://:secret@cache:6379/2", options{addr: "cache:6379", password: "secret", db: 2}, false},
+		{"rediss://cache:6379", options{}, true},
+		{"redis://cache:6379/two", options{}, true},
+	}
+	for _, tt := range tests {
+		got, err := parseURL(tt.url)
+		if (err != nil) != tt.err {
+			t.Errorf("parseURL(%q) error = %v, want error %v", tt.url, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseURL(%q) = %+v, want %+v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	srv := resptest.NewServer(t, "secret")
+	anonymous, err := Dial("redis://" + srv.Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer anonymous.Close()
+	if _, err := anonymous.Do("GET", "key"); err == nil {
+		t.Error("GET without the password succeeded")
+	}
+	if _, err := Dial("redis://:wrong@" + srv.Addr); err == nil {
+		t.Error("Dial with a wrong password succeeded")
+	}
+	c, err := Dial(srv.URL + "/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err := c.Do("SET", "key", "line\r\nbreak"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.Do("GET", "key"); err != nil || got != "line\r\nbreak" {
+		t.Errorf("GET key = %q, %v", got, err)
+	}
+	if got, err := c.Do("GET", "missing"); err != nil || got != nil {
+		t.Errorf("GET missing = %v, %v, want nil", got, err)
+	}
+	if got, err := c.Do("DEL", "key", "missing"); err != nil || got != int64(1) {
+		t.Errorf("DEL = %v, %v, want 1", got, err)
+	}
+	var replyErr Error
+	if _, err := c.Do("NOSUCHCOMMAND"); !errors.As(err, &replyErr) {
+		t.Errorf("unknown command error = %v, want an Error reply", err)
+	}
+
+	// the client dials again after the connection broke
+	srv.DropConnections()
+	c.Do("PING")
+	if got, err := c.Do("PING"); err != nil || got != "PONG" {
+		t.Errorf("PING after a dropped connection = %v, %v", got, err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	srv := resptest.NewServer(t, "")
+	c, err := Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	tests := []struct {
+		name    string
+		between func() // runs after the key is read, before EXEC
+		want    error
+		value   string
+	}{
+		{"nobody else", func() {}, nil, "1"},
+		{"changed by another client", func() { srv.Set("counter", "10") }, ErrAborted, "10"},
+		{"unchanged again", func() {}, nil, "11"},
+	}
+	for _, tt := range tests {
+		err := c.Watch([]string{"counter"}, func(tx *Tx) ([][]string, error) {
+			reply, err := tx.Do("GET", "counter")
+			if err != nil {
+				return nil, err
+			}
+			tt.between()
+			if reply == nil {
+				return [][]string{{"SET", "counter", "1"}}, nil
+			}
+			return [][]string{{"INCR", "counter"}}, nil
+		})
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: Watch = %v, want %v", tt.name, err, tt.want)
+		}
+		if got, _ := srv.Get("counter"); got != tt.value {
+			t.Errorf("%s: counter is %q, want %q", tt.name, got, tt.value)
+		}
+	}
+
+	// nothing to write, or an error, leaves the counter and the connection as they are
+	refused := errors.New("refused")
+	for _, fn := range []func(tx *Tx) ([][]string, error){
+		func(tx *Tx) ([][]string, error) { return nil, nil },
+		func(tx *Tx) ([][]string, error) { return [][]string{{"INCR", "counter"}}, refused },
+	} {
+		if err := c.Watch([]string{"counter"}, fn); err != nil && err != refused {
+			t.Fatal(err)
+		}
+		if got, err := c.Do("GET", "counter"); err != nil || got != "11" {
+			t.Errorf("GET after a transaction without writes = %v, %v", got, err)
+		}
+	}
+}
+
+func TestPubSub(t *testing.T) {
+	srv := resptest.NewServer(t, "")
+	c, err := Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	received := make(chan string, 10)
+	p, err := NewPubSub(srv.URL, func(channel string, payload []byte) {
+		received <- channel + ":" + string(payload)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	// publishes until the subscription is in place, the first ones may be
+	// lost and repeats of earlier ones are skipped
+	expect := func(channel, payload string) {
+		t.Helper()
+		deadline := time.After(5 * time.Second)
+		for {
+			c.Do("PUBLISH", channel, payload)
+			select {
+			case got := <-received:
+				if got == channel+":"+payload {
+					return
+				}
+			case <-deadline:
+				t.Fatalf("nothing received on %s", channel)
+			case <-time.After(20 * time.Millisecond):
+			}
+		}
+	}
+
+	if err := p.Subscribe("room"); err != nil {
+		t.Fatal(err)
+	}
+	expect("room", "rolled")
+
+	// subscribes again after the connection broke
+	srv.DropConnections()
+	expect("room", "written")
+
+	if err := p.Unsubscribe("room"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	for len(received) > 0 {
+		<-received
+	}
+	c.Do("PUBLISH", "room", "ended")
+	select {
+	case got := <-received:
+		t.Errorf("received %q after unsubscribing", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
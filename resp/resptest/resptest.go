@@ -0,0 +1,459 @@
+// Package resptest runs a stand-in for a Redis server on a local port, for
+// tests of code that uses package resp. It keeps string keys and hashes in
+// memory and knows the commands this module sends: strings, hashes, SCAN,
+// optimistic transactions and pub/sub.
+package resptest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Server is listening until the test ends
+type Server struct {
+	URL      string // with the password
+	Addr     string
+	password string
+
+	ln net.Listener
+
+	mu       sync.Mutex
+	strings  map[string]string
+	hashes   map[string]map[string]string
+	versions map[string]int       // bumped by every write of a key, for WATCH
+	expires  map[string]time.Time // keys with a PEXPIRE
+	clients  map[*client]bool
+}
+
+// NewServer starts a server, a non-empty password must be sent with AUTH
+func NewServer(t testing.TB, password string) *Server {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{
+		URL:      "redis://" + ln.Addr().String(),
+		Addr:     ln.Addr().String(),
+		password: password,
+		ln:       ln,
+		strings:  make(map[string]string),
+		hashes:   make(map[string]map[string]string),
+		versions: make(map[string]int),
+		expires:  make(map[string]time.Time),
+		clients:  make(map[*client]bool),
+	}
+	if password != "" {
+		s.URL = "redis://:" + password + "@" + s.Addr
+	}
+	go s.accept()
+	t.Cleanup(s.close)
+	return s
+}
+
+// Set writes a string key as another client would
+func (s *Server) Set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.strings[key] = value
+	s.versions[key]++
+	delete(s.expires, key)
+}
+
+// Get reads a string key
+func (s *Server) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.strings[key]
+	return value, ok
+}
+
+// DropConnections closes the connections of every client, like a restart
+// of the server would
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.clients {
+		c.conn.Close()
+		delete(s.clients, c)
+	}
+}
+
+func (s *Server) close() {
+	s.ln.Close()
+	s.DropConnections()
+}
+
+func (s *Server) accept() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		c := &client{server: s, conn: conn, authed: s.password == "", channels: make(map[string]bool)}
+		s.mu.Lock()
+		s.clients[c] = true
+		s.mu.Unlock()
+		go c.serve()
+	}
+}
+
+// status is a simple string reply, errorReply an error reply
+type (
+	status     string
+	errorReply string
+)
+
+type client struct {
+	server *Server
+	conn   net.Conn
+
+	writeMu sync.Mutex // publishes of other clients write to the connection too
+
+	authed   bool
+	watched  map[string]int // key -> version when it was watched
+	queued   [][]string     // commands after MULTI
+	multi    bool
+	channels map[string]bool
+}
+
+func (c *client) serve() {
+	defer func() {
+		c.conn.Close()
+		c.server.mu.Lock()
+		delete(c.server.clients, c)
+		c.server.mu.Unlock()
+	}()
+	r := bufio.NewReader(c.conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		c.write(c.run(args))
+	}
+}
+
+// readCommand reads an array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	n, err := readHeader(r, '*')
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		size, err := readHeader(r, '$')
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func readHeader(r *bufio.Reader, kind byte) (int, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+	if len(line) < 3 || line[0] != kind {
+		return 0, fmt.Errorf("expected %q, got %q", kind, line)
+	}
+	return strconv.Atoi(strings.TrimSuffix(line[1:], "\r\n"))
+}
+
+func (c *client) write(reply any) {
+	var b strings.Builder
+	encode(&b, reply)
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	io.WriteString(c.conn, b.String())
+}
+
+func encode(b *strings.Builder, reply any) {
+	switch v := reply.(type) {
+	case nil:
+		b.WriteString("$-1\r\n")
+	case status:
+		fmt.Fprintf(b, "+%s\r\n", v)
+	case errorReply:
+		fmt.Fprintf(b, "-%s\r\n", v)
+	case int:
+		fmt.Fprintf(b, ":%d\r\n", v)
+	case string:
+		fmt.Fprintf(b, "$%d\r\n%s\r\n", len(v), v)
+	case []any:
+		if v == nil {
+			b.WriteString("*-1\r\n")
+			return
+		}
+		fmt.Fprintf(b, "*%d\r\n", len(v))
+		for _, item := range v {
+			encode(b, item)
+		}
+	default:
+		panic(fmt.Sprintf("cannot encode %T", reply))
+	}
+}
+
+func (c *client) run(args []string) any {
+	if len(args) == 0 {
+		return errorReply("ERR empty command")
+	}
+	cmd := strings.ToUpper(args[0])
+	if cmd == "AUTH" {
+		if len(args) != 2 || c.server.password == "" || args[1] != c.server.password {
+			return errorReply("WRONGPASS invalid password")
+		}
+		c.authed = true
+		return status("OK")
+	}
+	if !c.authed {
+		return errorReply("NOAUTH Authentication required.")
+	}
+
+	c.server.mu.Lock()
+	defer c.server.mu.Unlock()
+	if c.multi && cmd != "EXEC" && cmd != "DISCARD" {
+		c.queued = append(c.queued, args)
+		return status("QUEUED")
+	}
+	switch cmd {
+	case "WATCH":
+		if c.watched == nil {
+			c.watched = make(map[string]int)
+		}
+		for _, key := range args[1:] {
+			c.watched[key] = c.server.versions[key]
+		}
+		return status("OK")
+	case "UNWATCH":
+		c.watched = nil
+		return status("OK")
+	case "MULTI":
+		c.multi = true
+		return status("OK")
+	case "DISCARD":
+		c.multi, c.queued, c.watched = false, nil, nil
+		return status("OK")
+	case "EXEC":
+		if !c.multi {
+			return errorReply("ERR EXEC without MULTI")
+		}
+		queued, watched := c.queued, c.watched
+		c.multi, c.queued, c.watched = false, nil, nil
+		for key, version := range watched {
+			if c.server.versions[key] != version {
+				return []any(nil)
+			}
+		}
+		replies := []any{}
+		for _, args := range queued {
+			replies = append(replies, c.command(args))
+		}
+		return replies
+	}
+	return c.command(args)
+}
+
+// command runs a command that is not about the connection, caller holds the
+// lock of the server
+func (c *client) command(args []string) any {
+	s := c.server
+	cmd := strings.ToUpper(args[0])
+	for key, at := range s.expires {
+		if !time.Now().Before(at) {
+			s.del(key)
+		}
+	}
+	argc := map[string]int{
+		"SELECT": 2, "PING": 1, "GET": 2, "INCR": 2, "PEXPIRE": 3,
+		"HGET": 3, "HGETALL": 2, "PUBLISH": 3,
+	}
+	if n, ok := argc[cmd]; ok && len(args) != n {
+		return errorReply("ERR wrong number of arguments for '" + strings.ToLower(cmd) + "' command")
+	}
+	switch cmd {
+	case "SELECT":
+		return status("OK")
+	case "PING":
+		return status("PONG")
+	case "GET":
+		if value, ok := s.strings[args[1]]; ok {
+			return value
+		}
+		return nil
+	case "SET":
+		// SET key value [PX milliseconds] [NX]
+		if len(args) < 3 {
+			return errorReply("ERR wrong number of arguments for 'set' command")
+		}
+		var ttl time.Duration
+		onlyNew := false
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				onlyNew = true
+			case "PX":
+				if i+1 == len(args) {
+					return errorReply("ERR syntax error")
+				}
+				ms, err := strconv.Atoi(args[i+1])
+				if err != nil {
+					return errorReply("ERR value is not an integer or out of range")
+				}
+				ttl = time.Duration(ms) * time.Millisecond
+				i++
+			default:
+				return errorReply("ERR syntax error")
+			}
+		}
+		if onlyNew && s.exists(args[1]) {
+			return nil
+		}
+		s.strings[args[1]] = args[2]
+		s.versions[args[1]]++
+		delete(s.expires, args[1])
+		if ttl > 0 {
+			s.expires[args[1]] = time.Now().Add(ttl)
+		}
+		return status("OK")
+	case "INCR":
+		n, _ := strconv.Atoi(s.strings[args[1]])
+		s.strings[args[1]] = strconv.Itoa(n + 1)
+		s.versions[args[1]]++
+		return n + 1
+	case "PEXPIRE":
+		ms, err := strconv.Atoi(args[2])
+		if err != nil {
+			return errorReply("ERR value is not an integer or out of range")
+		}
+		if !s.exists(args[1]) {
+			return 0
+		}
+		s.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		return 1
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if s.exists(key) {
+				s.del(key)
+				deleted++
+			}
+		}
+		return deleted
+	case "HGET":
+		if value, ok := s.hashes[args[1]][args[2]]; ok {
+			return value
+		}
+		return nil
+	case "HSET":
+		if len(args) < 4 || len(args)%2 != 0 {
+			return errorReply("ERR wrong number of arguments for 'hset' command")
+		}
+		h := s.hashes[args[1]]
+		if h == nil {
+			h = make(map[string]string)
+			s.hashes[args[1]] = h
+		}
+		added := 0
+		for i := 2; i < len(args); i += 2 {
+			if _, ok := h[args[i]]; !ok {
+				added++
+			}
+			h[args[i]] = args[i+1]
+		}
+		s.versions[args[1]]++
+		return added
+	case "HDEL":
+		h := s.hashes[args[1]]
+		deleted := 0
+		for _, field := range args[2:] {
+			if _, ok := h[field]; ok {
+				delete(h, field)
+				deleted++
+			}
+		}
+		if len(h) == 0 {
+			delete(s.hashes, args[1])
+		}
+		s.versions[args[1]]++
+		return deleted
+	case "HGETALL":
+		h := s.hashes[args[1]]
+		fields := make([]string, 0, len(h))
+		for field := range h {
+			fields = append(fields, field)
+		}
+		slices.Sort(fields)
+		reply := []any{}
+		for _, field := range fields {
+			reply = append(reply, field, h[field])
+		}
+		return reply
+	case "SCAN":
+		// one page with every matching key, the cursor is always done
+		pattern := "*"
+		for i := 2; i+1 < len(args); i += 2 {
+			if strings.ToUpper(args[i]) == "MATCH" {
+				pattern = args[i+1]
+			}
+		}
+		keys := []any{}
+		for key := range s.versions {
+			if ok, _ := path.Match(pattern, key); ok && s.exists(key) {
+				keys = append(keys, key)
+			}
+		}
+		return []any{"0", keys}
+	case "PUBLISH":
+		receivers := 0
+		for other := range s.clients {
+			if other.channels[args[1]] {
+				other.write([]any{"message", args[1], args[2]})
+				receivers++
+			}
+		}
+		return receivers
+	case "SUBSCRIBE", "UNSUBSCRIBE":
+		// one channel at a time, as package resp sends them
+		if len(args) != 2 {
+			return errorReply("ERR only one channel is supported")
+		}
+		if cmd == "SUBSCRIBE" {
+			c.channels[args[1]] = true
+		} else {
+			delete(c.channels, args[1])
+		}
+		return []any{strings.ToLower(cmd), args[1], len(c.channels)}
+	}
+	return errorReply("ERR unknown command '" + args[0] + "'")
+}
+
+// caller holds the lock of the server
+func (s *Server) exists(key string) bool {
+	_, isString := s.strings[key]
+	_, isHash := s.hashes[key]
+	return isString || isHash
+}
+
+// caller holds the lock of the server
+func (s *Server) del(key string) {
+	delete(s.strings, key)
+	delete(s.hashes, key)
+	delete(s.expires, key)
+	s.versions[key]++
+}
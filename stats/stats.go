@@ -0,0 +1,148 @@
+// Package stats computes player statistics from finished game records.
+package stats
+
+import (
+	"slices"
+	"time"
+	"yamb/game"
+	"yamb/record"
+)
+
+// lower section rows that are either scored or crossed out with zero
+var lowerRows = []string{game.Straight, game.FullHouse, game.Quads, game.Yamb}
+
+type Stats struct {
+	Games        int
+	Wins         int
+	AverageTotal float64
+	Modes        []ModeStats   // in order of game.Mode* constants, only played modes
+	Columns      []ColumnStats // in scorecard order
+	Rows         []RowStats    // lower section rows
+	Best         *BestGame     // nil without games
+}
+
+type ModeStats struct {
+	Mode  string
+	Games int
+	Wins  int
+}
+
+func (m ModeStats) WinRate() float64 {
+	return rate(m.Wins, m.Games)
+}
+
+// average of the three sums of the column
+type ColumnStats struct {
+	Col     string
+	Games   int
+	Average float64
+}
+
+type RowStats struct {
+	Row    string
+	Scored int // written with points
+	Zero   int // crossed out
+}
+
+func (r RowStats) ScoredRate() float64 {
+	return rate(r.Scored, r.Scored+r.Zero)
+}
+
+type BestGame struct {
+	RoomID     string
+	Mode       string
+	Total      int
+	FinishedAt time.Time
+}
+
+func (s Stats) WinRate() float64 {
+	return rate(s.Wins, s.Games)
+}
+
+func rate(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total)
+}
+
+// Compute the stats of the account from the games it played
+func Compute(accountID string, games []*record.Record) Stats {
+	s := Stats{}
+	modes := map[string]*ModeStats{}
+	columns := map[string]*ColumnStats{}
+	columnOrder := []string{}
+	rows := map[string]*RowStats{}
+	for _, row := range lowerRows {
+		rows[row] = &RowStats{Row: row}
+	}
+	totals := 0
+
+	for _, rec := range games {
+		idx := slices.IndexFunc(rec.Players, func(p record.PlayerRecord) bool {
+			return p.AccountID == accountID
+		})
+		if idx == -1 {
+			continue
+		}
+		p := rec.Players[idx]
+
+		s.Games++
+		totals += p.Total
+		if modes[rec.Mode] == nil {
+			modes[rec.Mode] = &ModeStats{Mode: rec.Mode}
+		}
+		modes[rec.Mode].Games++
+		if rec.Won(idx) {
+			s.Wins++
+			modes[rec.Mode].Wins++
+		}
+		if s.Best == nil || p.Total > s.Best.Total {
+			s.Best = &BestGame{RoomID: rec.RoomID, Mode: rec.Mode, Total: p.Total, FinishedAt: rec.FinishedAt}
+		}
+
+		colSums := map[string]int{}
+		for _, c := range p.Cells {
+			if c.Score == nil {
+				continue
+			}
+			switch c.Row {
+			case game.Sum1, game.Sum2, game.Sum3:
+				if columns[c.Col] == nil {
+					columnOrder = append(columnOrder, c.Col)
+					columns[c.Col] = &ColumnStats{Col: c.Col}
+				}
+				colSums[c.Col] += *c.Score
+			}
+			if row, ok := rows[c.Row]; ok {
+				if *c.Score == 0 {
+					row.Zero++
+				} else {
+					row.Scored++
+				}
+			}
+		}
+		for col, sum := range colSums {
+			cs := columns[col]
+			// running average, columns differ between rulesets
+			cs.Average = (cs.Average*float64(cs.Games) + float64(sum)) / float64(cs.Games+1)
+			cs.Games++
+		}
+	}
+
+	if s.Games > 0 {
+		s.AverageTotal = float64(totals) / float64(s.Games)
+	}
+	for _, mode := range []string{game.Mode1v1, game.Mode1v1v1, game.Mode2v2} {
+		if m, ok := modes[mode]; ok {
+			s.Modes = append(s.Modes, *m)
+		}
+	}
+	for _, col := range columnOrder {
+		s.Columns = append(s.Columns, *columns[col])
+	}
+	for _, row := range lowerRows {
+		s.Rows = append(s.Rows, *rows[row])
+	}
+	return s
+}
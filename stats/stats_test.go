@@ -0,0 +1,119 @@
+package stats
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"yamb/game"
+	"yamb/record"
+)
+
+// played plays a whole game of the mode between the accounts, every player
+// writing the first cell the dice allow, and returns its record with the
+// totals replaced
+func played(t *testing.T, mode string, accounts []string, totals []int) *record.Record {
+	t.Helper()
+	r := game.NewRoom(mode, "6", game.RulesClassic)
+	r.ID = "123456"
+	for i, accountID := range accounts {
+		token := game.NewToken()
+		if i == 0 {
+			r.SetHost(token)
+		}
+		p := r.NewPlayer(token, accountID)
+		p.AccountID = accountID
+		if err := r.AddPlayer(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range r.Players {
+		if err := r.SetReady(p.ID, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Start(r.HostID); err != nil {
+		t.Fatal(err)
+	}
+	for r.GetState() == game.StatePlaying {
+		playTurn(t, r)
+	}
+
+	rec, err := record.New(r.ID, r.Events())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range rec.Players {
+		rec.Players[i].Total = totals[slices.Index(accounts, rec.Players[i].AccountID)]
+	}
+	return rec
+}
+
+// playTurn rolls once and writes the first cell the dice allow
+func playTurn(t *testing.T, r *game.Room) {
+	t.Helper()
+	playerID := r.CurrentPlayerID()
+	if err := r.RollDice(playerID); err != nil {
+		t.Fatal(err)
+	}
+	p := r.GetPlayerByID(playerID)
+	for _, col := range p.ScoreCard.Columns {
+		for _, row := range p.ScoreCard.Rows {
+			if p.ScoreCard.Scores[row.ID][col.ID] == nil && write(r, playerID, row.ID, col.ID) {
+				return
+			}
+		}
+	}
+	t.Fatalf("%s cannot write anything", playerID)
+}
+
+// write holds the dice the row needs and writes the cell
+func write(r *game.Room, playerID, row, col string) bool {
+	if r.SelectCell(playerID, row, col) != nil {
+		return false
+	}
+	if col == game.Announced && r.Announce(playerID) != nil {
+		return false
+	}
+	want := func(i, _ int) bool { return i < 5 }
+	if n, err := strconv.Atoi(row); err == nil {
+		want = func(_, v int) bool { return v == n }
+	} else if row == game.Quads {
+		want = func(i, _ int) bool { return i < 4 }
+	}
+	for i, v := range r.Dice.Values {
+		if r.Dice.Held[i] != want(i, v) {
+			r.ToggleDie(playerID, i)
+		}
+	}
+	_, err := r.WriteScore(playerID)
+	return err == nil
+}
+
+func TestComputeWins(t *testing.T) {
+	games := []*record.Record{
+		// best total on the losing side, partners sit across the table
+		played(t, game.Mode2v2, []string{"a", "b", "c", "d"}, []int{900, 600, 300, 700}),
+		played(t, game.Mode1v1, []string{"a", "b"}, []int{700, 650}),
+	}
+
+	tests := []struct {
+		account  string
+		wins     int
+		modeWins map[string]int
+	}{
+		{"a", 1, map[string]int{game.Mode1v1: 1, game.Mode2v2: 0}},
+		{"b", 1, map[string]int{game.Mode1v1: 0, game.Mode2v2: 1}},
+		{"d", 1, map[string]int{game.Mode2v2: 1}},
+	}
+	for _, tt := range tests {
+		s := Compute(tt.account, games)
+		if s.Wins != tt.wins {
+			t.Errorf("%s: %d wins, want %d", tt.account, s.Wins, tt.wins)
+		}
+		for _, m := range s.Modes {
+			if m.Wins != tt.modeWins[m.Mode] {
+				t.Errorf("%s: %d wins in %s, want %d", tt.account, m.Wins, m.Mode, tt.modeWins[m.Mode])
+			}
+		}
+	}
+}
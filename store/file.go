@@ -0,0 +1,117 @@
+package store
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"yamb/game"
+)
+
+// FileStore keeps one JSON snapshot per room in a directory
+type FileStore struct {
+	dir string
+
+	mu    sync.Mutex
+	saves map[string]*sync.Mutex // room id -> held while saving the room
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir, saves: make(map[string]*sync.Mutex)}, nil
+}
+
+func (s *FileStore) path(roomID string) string {
+	return filepath.Join(s.dir, roomID+".json")
+}
+
+func (s *FileStore) Save(room *game.Room) error {
+	if room.ID == "" {
+		return errors.New("room has no id")
+	}
+	// snapshots of one room are written in the order they are taken, an
+	// older one must not replace a newer one
+	saving := s.saveLock(room.ID)
+	saving.Lock()
+	defer saving.Unlock()
+
+	data, err := room.Snapshot()
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first so a crash never leaves half a snapshot
+	tmp, err := os.CreateTemp(s.dir, room.ID+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(room.ID))
+}
+
+func (s *FileStore) saveLock(roomID string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saves[roomID] == nil {
+		s.saves[roomID] = &sync.Mutex{}
+	}
+	return s.saves[roomID]
+}
+
+func (s *FileStore) Delete(roomID string) error {
+	saving := s.saveLock(roomID)
+	saving.Lock()
+	defer saving.Unlock()
+	err := os.Remove(s.path(roomID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileStore) Load(roomID string) (*game.Room, error) {
+	data, err := os.ReadFile(s.path(roomID))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return game.RestoreRoom(data)
+}
+
+func (s *FileStore) LoadAll() ([]*game.Room, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	rooms := []*game.Room{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		room, err := game.RestoreRoom(data)
+		if err != nil {
+			// one broken snapshot should not keep the server from starting
+			log.Printf("skipping room snapshot %s: %v", entry.Name(), err)
+			continue
+		}
+		rooms = append(rooms, room)
+	}
+	return rooms, nil
+}
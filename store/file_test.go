@@ -0,0 +1,48 @@
+package store
+
+import (
+	"sync"
+	"testing"
+	"yamb/game"
+)
+
+// whatever order concurrent saves finish in, the file holds the last change
+func TestFileStoreKeepsLatestSave(t *testing.T) {
+	s, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	room := game.NewRoom(game.Mode1v1, "6", game.RulesClassic)
+	room.ID = "123456"
+
+	const changes = 50
+	var wg sync.WaitGroup
+	for range changes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			room.Mu.Lock()
+			room.ChatHistory = append(room.ChatHistory, game.NewChatMessage("p", "hi"))
+			room.Mu.Unlock()
+			if err := s.Save(room); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	saved, err := s.Load(room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.ChatHistory) != changes {
+		t.Errorf("saved room has %d of %d changes", len(saved.ChatHistory), changes)
+	}
+
+	if err := s.Delete(room.ID); err != nil {
+		t.Fatal(err)
+	}
+	if saved, err := s.Load(room.ID); err != nil || saved != nil {
+		t.Errorf("Load after Delete = %v, %v", saved, err)
+	}
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"yamb/game"
+	"yamb/resp"
+)
+
+const roomKeyPrefix = "yamb:room:"
+
+// RedisStore keeps one JSON snapshot per room under a Redis key, shared by
+// every instance connected to the same server. A snapshot replaces the saved
+// one only if it is the next version of it (compare-and-set with WATCH), so
+// two instances changing a room at once cannot overwrite each other.
+type RedisStore struct {
+	client *resp.Client
+
+	mu    sync.Mutex
+	saves map[string]*sync.Mutex // room id -> held while the room is saved
+}
+
+func NewRedisStore(client *resp.Client) *RedisStore {
+	return &RedisStore{client: client, saves: make(map[string]*sync.Mutex)}
+}
+
+func (s *RedisStore) Save(room *game.Room) error {
+	if room.ID == "" {
+		return errors.New("room has no id")
+	}
+	// saves of this instance follow each other, each one the next version
+	saving := s.saveLock(room.ID)
+	saving.Lock()
+	defer saving.Unlock()
+
+	data, version, err := room.NextSnapshot()
+	if err != nil {
+		return err
+	}
+	key := roomKeyPrefix + room.ID
+	err = s.client.Watch([]string{key}, func(tx *resp.Tx) ([][]string, error) {
+		reply, err := tx.Do("GET", key)
+		if err != nil {
+			return nil, err
+		}
+		saved, err := savedVersion(reply)
+		if err != nil {
+			return nil, err
+		}
+		if saved != version-1 {
+			return nil, ErrConflict
+		}
+		return [][]string{{"SET", key, string(data)}}, nil
+	})
+	if errors.Is(err, resp.ErrAborted) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	room.SetVersion(version)
+	return nil
+}
+
+// savedVersion reads the version of a snapshot, 0 when there is none
+func savedVersion(reply any) (int, error) {
+	if reply == nil {
+		return 0, nil
+	}
+	data, ok := reply.(string)
+	if !ok {
+		return 0, errors.New("unexpected reply to GET")
+	}
+	var saved struct{ Version int }
+	err := json.Unmarshal([]byte(data), &saved)
+	return saved.Version, err
+}
+
+func (s *RedisStore) saveLock(roomID string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saves[roomID] == nil {
+		s.saves[roomID] = &sync.Mutex{}
+	}
+	return s.saves[roomID]
+}
+
+func (s *RedisStore) Delete(roomID string) error {
+	saving := s.saveLock(roomID)
+	saving.Lock()
+	defer saving.Unlock()
+	_, err := s.client.Do("DEL", roomKeyPrefix+roomID)
+	return err
+}
+
+func (s *RedisStore) Load(roomID string) (*game.Room, error) {
+	reply, err := s.client.Do("GET", roomKeyPrefix+roomID)
+	if err != nil || reply == nil {
+		return nil, err
+	}
+	data, ok := reply.(string)
+	if !ok {
+		return nil, errors.New("unexpected reply to GET")
+	}
+	return game.RestoreRoom([]byte(data))
+}
+
+func (s *RedisStore) LoadAll() ([]*game.Room, error) {
+	rooms := []*game.Room{}
+	cursor := "0"
+	for {
+		reply, err := s.client.Do("SCAN", cursor, "MATCH", roomKeyPrefix+"*", "COUNT", "100")
+		if err != nil {
+			return nil, err
+		}
+		page, ok := reply.([]any)
+		if !ok || len(page) != 2 {
+			return nil, errors.New("unexpected reply to SCAN")
+		}
+		cursor, _ = page[0].(string)
+		keys, _ := page[1].([]any)
+		for _, key := range keys {
+			k, _ := key.(string)
+			room, err := s.Load(k[len(roomKeyPrefix):])
+			if err != nil {
+				// one broken snapshot should not keep the server from starting
+				log.Printf("skipping room snapshot %s: %v", k, err)
+				continue
+			}
+			if room != nil {
+				rooms = append(rooms, room)
+			}
+		}
+		if cursor == "0" {
+			return rooms, nil
+		}
+	}
+}
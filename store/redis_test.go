@@ -0,0 +1,117 @@
+package store
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"yamb/game"
+	"yamb/resp"
+	"yamb/resp/resptest"
+)
+
+func newRedisStore(t *testing.T, srv *resptest.Server) *RedisStore {
+	t.Helper()
+	client, err := resp.Dial(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return NewRedisStore(client)
+}
+
+func TestRedisStore(t *testing.T) {
+	s := newRedisStore(t, resptest.NewServer(t, ""))
+	room := game.NewRoom(game.Mode1v1, "6", game.RulesClassic)
+	room.ID = "123456"
+
+	// concurrent saves of one instance follow each other
+	const changes = 50
+	var wg sync.WaitGroup
+	for range changes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			room.Mu.Lock()
+			room.ChatHistory = append(room.ChatHistory, game.NewChatMessage("p", "hi"))
+			room.Mu.Unlock()
+			if err := s.Save(room); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	saved, err := s.Load(room.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.ChatHistory) != changes || saved.Version != changes {
+		t.Errorf("saved room has %d of %d changes, version %d", len(saved.ChatHistory), changes, saved.Version)
+	}
+	all, err := s.LoadAll()
+	if err != nil || len(all) != 1 || all[0].ID != room.ID {
+		t.Errorf("LoadAll = %v, %v", all, err)
+	}
+
+	if err := s.Delete(room.ID); err != nil {
+		t.Fatal(err)
+	}
+	if saved, err := s.Load(room.ID); err != nil || saved != nil {
+		t.Errorf("Load after Delete = %v, %v", saved, err)
+	}
+}
+
+// two instances change the same room, the one that saves second has to reload
+func TestRedisStoreConflict(t *testing.T) {
+	srv := resptest.NewServer(t, "")
+	first, second := newRedisStore(t, srv), newRedisStore(t, srv)
+	room := game.NewRoom(game.Mode1v1, "6", game.RulesClassic)
+	room.ID = "123456"
+	if err := first.Save(room); err != nil {
+		t.Fatal(err)
+	}
+
+	load := func(s *RedisStore) *game.Room {
+		t.Helper()
+		r, err := s.Load(room.ID)
+		if err != nil || r == nil {
+			t.Fatalf("Load = %v, %v", r, err)
+		}
+		return r
+	}
+	chat := func(r *game.Room, msg string) {
+		r.ChatHistory = append(r.ChatHistory, game.NewChatMessage("p", msg))
+	}
+	a, b := load(first), load(second)
+
+	chat(a, "first")
+	if err := first.Save(a); err != nil {
+		t.Fatal(err)
+	}
+	chat(b, "second")
+	if err := second.Save(b); !errors.Is(err, ErrConflict) {
+		t.Fatalf("saving over a newer version = %v, want %v", err, ErrConflict)
+	}
+	if got := load(first); len(got.ChatHistory) != 1 || got.ChatHistory[0].Message != "first" {
+		t.Errorf("the refused save replaced the room: %+v", got.ChatHistory)
+	}
+
+	// after reloading the room the change goes through
+	b.Refresh(load(second))
+	chat(b, "second")
+	if err := second.Save(b); err != nil {
+		t.Fatal(err)
+	}
+	if got := load(first); len(got.ChatHistory) != 2 {
+		t.Errorf("saved room has %d messages, want 2", len(got.ChatHistory))
+	}
+
+	// a deleted room does not come back from an instance that still has it
+	if err := first.Delete(room.ID); err != nil {
+		t.Fatal(err)
+	}
+	chat(b, "third")
+	if err := second.Save(b); !errors.Is(err, ErrConflict) {
+		t.Errorf("saving a deleted room = %v, want %v", err, ErrConflict)
+	}
+}
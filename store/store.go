@@ -0,0 +1,26 @@
+// Package store keeps rooms outside of the process memory so that games
+// survive a restart of the server, and with RedisStore so that several
+// instances can serve the same rooms.
+package store
+
+import (
+	"errors"
+	"yamb/game"
+)
+
+// ErrConflict is returned by Save when another instance saved the room after
+// this one loaded it, the room has to be reloaded before it changes again
+var ErrConflict = errors.New("room was saved by another instance")
+
+type RoomStore interface {
+	// Save stores the current state of the room, replacing the previous one.
+	// Stores shared by several instances return ErrConflict instead of
+	// replacing a version the room has not seen.
+	Save(room *game.Room) error
+	// Delete forgets the room, deleting a missing room is not an error
+	Delete(roomID string) error
+	// Load restores one room, nil when it was not saved
+	Load(roomID string) (*game.Room, error)
+	// LoadAll restores every saved room
+	LoadAll() ([]*game.Room, error)
+}
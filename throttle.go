@@ -0,0 +1,148 @@
+package main
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"yamb/resp"
+)
+
+// Throttle limits failed attempts (e.g. wrong room passphrases) per client IP.
+// After maxFailures failures within window the IP is blocked for the rest of the window.
+type Throttle struct {
+	mu          sync.Mutex
+	attempts    map[string]*attempts
+	swept       time.Time // last time expired attempts were removed
+	maxFailures int
+	window      time.Duration
+
+	// counts the failures in redis instead, see Share
+	shared *resp.Client
+	name   string
+}
+
+type attempts struct {
+	failures int
+	since    time.Time
+}
+
+func NewThrottle(maxFailures int, window time.Duration) *Throttle {
+	return &Throttle{
+		attempts:    make(map[string]*attempts),
+		maxFailures: maxFailures,
+		window:      window,
+	}
+}
+
+// Share counts the failures in Redis under the name, so that every instance
+// connected to it blocks an IP that failed on any of them. When Redis cannot
+// be reached the failures are not counted rather than everyone being blocked.
+// Call it before the server starts.
+func (t *Throttle) Share(client *resp.Client, name string) {
+	t.shared, t.name = client, name
+}
+
+// key of the failures of the IP in redis, it expires with the window
+func (t *Throttle) key(ip string) string {
+	return "yamb:throttle:" + t.name + ":" + ip
+}
+
+// Allowed reports whether the IP may try again
+func (t *Throttle) Allowed(ip string) bool {
+	if t.shared != nil {
+		reply, err := t.shared.Do("GET", t.key(ip))
+		if err != nil {
+			log.Println("error reading throttle:", err)
+			return true
+		}
+		failures, _ := reply.(string)
+		n, _ := strconv.Atoi(failures)
+		return n < t.maxFailures
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	a, ok := t.attempts[ip]
+	if !ok {
+		return true
+	}
+	if time.Since(a.since) > t.window {
+		delete(t.attempts, ip)
+		return true
+	}
+	return a.failures < t.maxFailures
+}
+
+func (t *Throttle) Fail(ip string) {
+	if t.shared != nil {
+		// the first failure starts the window, the others count in it
+		key, window := t.key(ip), strconv.FormatInt(t.window.Milliseconds(), 10)
+		err := t.shared.Watch(nil, func(*resp.Tx) ([][]string, error) {
+			return [][]string{{"SET", key, "0", "PX", window, "NX"}, {"INCR", key}}, nil
+		})
+		if err != nil {
+			log.Println("error counting failure:", err)
+		}
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sweep()
+	a, ok := t.attempts[ip]
+	if !ok || time.Since(a.since) > t.window {
+		a = &attempts{since: time.Now()}
+		t.attempts[ip] = a
+	}
+	a.failures++
+}
+
+func (t *Throttle) Reset(ip string) {
+	if t.shared != nil {
+		if _, err := t.shared.Do("DEL", t.key(ip)); err != nil {
+			log.Println("error resetting throttle:", err)
+		}
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.attempts, ip)
+}
+
+// sweep forgets the IPs whose window is over, at most once per window,
+// caller must hold the lock
+func (t *Throttle) sweep() {
+	if time.Since(t.swept) < t.window {
+		return
+	}
+	for ip, a := range t.attempts {
+		if time.Since(a.since) > t.window {
+			delete(t.attempts, ip)
+		}
+	}
+	t.swept = time.Now()
+}
+
+// behind a reverse proxy (TRUST_PROXY=true) the client IP is the one the proxy
+// added to X-Forwarded-For, without one anyone could send the header
+var trustProxy bool
+
+// clientIP returns the IP of the client
+func clientIP(r *http.Request) string {
+	if trustProxy {
+		forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+		if ip := strings.TrimSpace(forwarded[len(forwarded)-1]); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+	"yamb/resp"
+	"yamb/resp/resptest"
+)
+
+// every case returns two instances of a throttle, shared ones count together
+var throttles = []struct {
+	name string
+	new  func(t *testing.T, window time.Duration) (*Throttle, *Throttle)
+}{
+	{"memory", func(t *testing.T, window time.Duration) (*Throttle, *Throttle) {
+		th := NewThrottle(2, window)
+		return th, th
+	}},
+	{"shared", func(t *testing.T, window time.Duration) (*Throttle, *Throttle) {
+		srv := resptest.NewServer(t, "")
+		instances := make([]*Throttle, 2)
+		for i := range instances {
+			client, err := resp.Dial(srv.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { client.Close() })
+			instances[i] = NewThrottle(2, window)
+			instances[i].Share(client, "login")
+		}
+		return instances[0], instances[1]
+	}},
+}
+
+func TestThrottle(t *testing.T) {
+	for _, tt := range throttles {
+		a, b := tt.new(t, time.Minute)
+		a.Fail("1.2.3.4")
+		if !b.Allowed("1.2.3.4") {
+			t.Errorf("%s: blocked after one failure", tt.name)
+		}
+		b.Fail("1.2.3.4")
+		if a.Allowed("1.2.3.4") || b.Allowed("1.2.3.4") {
+			t.Errorf("%s: allowed after two failures", tt.name)
+		}
+		if !a.Allowed("5.6.7.8") {
+			t.Errorf("%s: another IP is blocked", tt.name)
+		}
+		a.Reset("1.2.3.4")
+		if !b.Allowed("1.2.3.4") {
+			t.Errorf("%s: blocked after a reset", tt.name)
+		}
+	}
+}
+
+func TestThrottleWindow(t *testing.T) {
+	for _, tt := range throttles {
+		a, b := tt.new(t, 100*time.Millisecond)
+		a.Fail("1.2.3.4")
+		time.Sleep(60 * time.Millisecond)
+		// later failures do not extend the window
+		b.Fail("1.2.3.4")
+		if a.Allowed("1.2.3.4") {
+			t.Errorf("%s: allowed after two failures", tt.name)
+		}
+		time.Sleep(60 * time.Millisecond)
+		if !a.Allowed("1.2.3.4") {
+			t.Errorf("%s: blocked after the window", tt.name)
+		}
+	}
+}
+
+func TestThrottleForgetsOldAttempts(t *testing.T) {
+	th := NewThrottle(2, time.Minute)
+	th.Fail("1.2.3.4")
+	th.attempts["1.2.3.4"].since = time.Now().Add(-2 * time.Minute)
+	th.swept = time.Time{}
+
+	th.Fail("5.6.7.8")
+	if _, ok := th.attempts["1.2.3.4"]; ok {
+		t.Error("expired attempts are kept")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	defer func() { trustProxy = false }()
+
+	tests := []struct {
+		trustProxy bool
+		forwarded  string
+		want       string
+	}{
+		{false, "", "10.0.0.1"},
+		{false, "1.2.3.4", "10.0.0.1"},
+		{true, "", "10.0.0.1"},
+		{true, "1.2.3.4", "1.2.3.4"},
+		// the client wrote the first entry, the proxy the last
+		{true, "6.6.6.6, 1.2.3.4", "1.2.3.4"},
+	}
+	for _, tt := range tests {
+		trustProxy = tt.trustProxy
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		if tt.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if got := clientIP(r); got != tt.want {
+			t.Errorf("trustProxy=%v X-Forwarded-For=%q: clientIP = %q, want %q", tt.trustProxy, tt.forwarded, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"yamb/record"
+	"yamb/views"
+)
+
+// game records are small, a full 3 player game is well under a megabyte
+const maxRecordSize = 4 << 20
+
+func VerifyPageHandler(w http.ResponseWriter, r *http.Request) {
+	lang := getLang(r)
+
+	err := views.VerifyPage(lang).Render(r.Context(), w)
+	if err != nil {
+		HxError(w, "could not render verify page", http.StatusInternalServerError)
+		log.Println("error rendering verify page:", err)
+		return
+	}
+}
+
+// VerifyRecordHandler checks an uploaded game record (see cmd/yamb-verify)
+func VerifyRecordHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRecordSize)
+	file, _, err := r.FormFile("record")
+	if err != nil {
+		HxError(w, "no game record uploaded", http.StatusBadRequest)
+		log.Println("error reading uploaded record:", err)
+		return
+	}
+	defer file.Close()
+
+	rec, err := record.Read(file)
+	if err != nil {
+		HxError(w, "not a valid game record", http.StatusBadRequest)
+		log.Println("error decoding uploaded record:", err)
+		return
+	}
+
+	lang := getLang(r)
+
+	err = views.VerifyResult(lang, rec, record.Verify(rec)).Render(r.Context(), w)
+	if err != nil {
+		HxError(w, "could not render verify result", http.StatusInternalServerError)
+		log.Println("error rendering verify result:", err)
+		return
+	}
+}
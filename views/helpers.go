@@ -0,0 +1,32 @@
+package views
+
+import (
+	"strings"
+	"yamb/broadcaster"
+	"yamb/game"
+)
+
+// public address of the app, used for shareable links
+const siteURL = "https://yamb-xnuq.onrender.com"
+
+// i18n key for the game mode label
+func modeKey(mode string) string {
+	switch mode {
+	case game.Mode1v1v1:
+		return "one_vs_one_vs_one"
+	case game.Mode2v2:
+		return "two_vs_two"
+	case game.ModeSolo:
+		return "solo"
+	}
+	return "one_vs_one"
+}
+
+// hx-trigger value listening to the given SSE events
+func sseTrigger(events ...broadcaster.EventName) string {
+	triggers := make([]string, len(events))
+	for i, ev := range events {
+		triggers[i] = "sse:" + string(ev)
+	}
+	return strings.Join(triggers, ", ")
+}